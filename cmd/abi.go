@@ -0,0 +1,37 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/ethereum_parser/internal/config"
+	"github.com/ethereum_parser/internal/decoder"
+	"github.com/ethereum_parser/internal/types"
+)
+
+func handleABIRegister(cfg *config.Config, address, file string) {
+	if address == "" || file == "" {
+		log.Fatalf("Both --address and --file are required for the 'abi register' command")
+	}
+
+	contract, err := types.ParseAddress(address)
+	if err != nil {
+		log.Fatalf("Invalid contract address: %v", err)
+	}
+
+	abiJSON, err := os.ReadFile(file)
+	if err != nil {
+		log.Fatalf("Failed to read ABI file: %v", err)
+	}
+
+	registry, err := decoder.NewRegistry(cfg.ABIDir)
+	if err != nil {
+		log.Fatalf("Failed to load ABI registry: %v", err)
+	}
+
+	if err := registry.Register(contract, abiJSON); err != nil {
+		log.Fatalf("Failed to register ABI: %v", err)
+	}
+
+	log.Printf("Registered ABI for %s in %s", contract, cfg.ABIDir)
+}
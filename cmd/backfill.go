@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ethereum_parser/internal/config"
+	"github.com/ethereum_parser/internal/parser"
+	"github.com/ethereum_parser/internal/types"
+)
+
+func handleBackfill(cfg *config.Config, addresses string, fromBlock, toBlock int64, jobID string) {
+	setupLogging(cfg.LogLevel)
+
+	if jobID == "" && addresses == "" {
+		log.Fatalf("Either --address or --job is required for the 'backfill' command")
+	}
+	if cfg.StorageBackend == "memory" {
+		log.Printf("Warning: backfilling into memory storage, results are lost on exit")
+	}
+
+	store, err := openStorage(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	defer store.Close()
+
+	ethParser, err := parser.NewEthereumParser(store, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize parser: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Resume an existing job or create a new one
+	if jobID == "" {
+		var parsed []types.Address
+		for _, a := range strings.Split(addresses, ",") {
+			address, err := types.ParseAddress(strings.TrimSpace(a))
+			if err != nil {
+				log.Fatalf("Invalid address: %v", err)
+			}
+			parsed = append(parsed, address)
+		}
+
+		job, err := ethParser.CreateBackfillJob(ctx, parsed, fromBlock, toBlock)
+		if err != nil {
+			log.Fatalf("Failed to create backfill job: %v", err)
+		}
+		jobID = job.ID
+		log.Printf("Created backfill job %s for blocks %d-%d", job.ID, job.FromBlock, job.ToBlock)
+	}
+
+	if err := ethParser.RunBackfill(ctx, jobID); err != nil {
+		if ctx.Err() != nil {
+			log.Printf("Backfill job %s interrupted, resume it with --job=%s", jobID, jobID)
+			return
+		}
+		log.Fatalf("Backfill failed: %v", err)
+	}
+}
@@ -21,6 +21,8 @@ func main() {
 	startCmd := flag.NewFlagSet("start", flag.ExitOnError)
 	sendCmd := flag.NewFlagSet("send", flag.ExitOnError)
 	createKeyCmd := flag.NewFlagSet("create_key", flag.ExitOnError)
+	backfillCmd := flag.NewFlagSet("backfill", flag.ExitOnError)
+	abiRegisterCmd := flag.NewFlagSet("abi register", flag.ExitOnError)
 
 	// Create a configuration object
 	cfg := config.NewConfig()
@@ -29,6 +31,26 @@ func main() {
 	startCmd.StringVar(&cfg.EthereumRPCURL, "rpc-url", cfg.EthereumRPCURL, "Ethereum RPC URL")
 	startCmd.IntVar(&cfg.HTTPPort, "port", cfg.HTTPPort, "HTTP server port")
 	startCmd.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "Logging level (debug, info, warn, error)")
+	startCmd.DurationVar(&cfg.PollInterval, "poll-interval", cfg.PollInterval, "Interval between block polls")
+	startCmd.DurationVar(&cfg.PollTimeout, "poll-timeout", cfg.PollTimeout, "Timeout for a single poll cycle")
+	startCmd.StringVar(&cfg.StorageBackend, "storage", cfg.StorageBackend, "Storage backend (memory, disk)")
+	startCmd.StringVar(&cfg.DataDir, "data-dir", cfg.DataDir, "Data directory for the disk storage backend")
+	startCmd.IntVar(&cfg.CatchupBatchSize, "catchup-batch-size", cfg.CatchupBatchSize, "Maximum number of blocks processed per poll cycle")
+	startCmd.IntVar(&cfg.ReorgWindow, "reorg-window", cfg.ReorgWindow, "Number of recent blocks tracked for reorg detection")
+	startCmd.StringVar(&cfg.ConfirmationPolicy, "confirmation-policy", cfg.ConfirmationPolicy, "Blocks to index (latest, confirmations, safe, finalized)")
+	startCmd.IntVar(&cfg.Confirmations, "confirmations", cfg.Confirmations, "Confirmations required before a transaction is confirmed")
+	startCmd.IntVar(&cfg.FetchWorkers, "fetch-workers", cfg.FetchWorkers, "Number of blocks fetched concurrently")
+	startCmd.StringVar(&cfg.TraceMode, "trace-mode", cfg.TraceMode, "Index internal transfers from call traces (debug, trace); empty disables")
+	startCmd.StringVar(&cfg.ABIDir, "abi-dir", cfg.ABIDir, "Directory of contract ABIs used to decode transaction input")
+	startCmd.BoolVar(&cfg.MempoolWatch, "mempool", cfg.MempoolWatch, "Track pending transactions of subscribed addresses")
+	startCmd.DurationVar(&cfg.MempoolInterval, "mempool-interval", cfg.MempoolInterval, "Interval between mempool polls")
+	startCmd.DurationVar(&cfg.PendingDropTimeout, "pending-drop-timeout", cfg.PendingDropTimeout, "Time after which an unmined pending transaction is checked for being dropped")
+	startCmd.Var(&cfg.RPCEndpoints, "rpc-urls", "Comma-separated pool of RPC URLs, each optionally suffixed with |weight; overrides --rpc-url")
+	startCmd.IntVar(&cfg.MaxHeadLag, "max-head-lag", cfg.MaxHeadLag, "Blocks an RPC endpoint may trail the observed head before it is ejected")
+	startCmd.DurationVar(&cfg.ProviderProbation, "provider-probation", cfg.ProviderProbation, "Time an ejected RPC endpoint is kept out of rotation")
+	startCmd.IntVar(&cfg.Quorum, "quorum", cfg.Quorum, "Number of RPC endpoints that must agree on a block before it is indexed; 0 disables")
+	startCmd.Float64Var(&cfg.RPCRateLimit, "rpc-rate-limit", cfg.RPCRateLimit, "Maximum average calls per second to each RPC endpoint; 0 disables")
+	startCmd.IntVar(&cfg.RPCRateBurst, "rpc-rate-burst", cfg.RPCRateBurst, "Calls that may be sent to an RPC endpoint at once under --rpc-rate-limit")
 
 	// Define flags for the "send" subcommand
 	privateKey := sendCmd.String("private-key", "", "Sender's private key")
@@ -36,9 +58,31 @@ func main() {
 	value := sendCmd.String("value", "", "Value to send (in wei)")
 	sendCmd.StringVar(&cfg.EthereumRPCURL, "rpc-url", cfg.EthereumRPCURL, "Ethereum RPC URL")
 
+	// Define flags for the "backfill" subcommand
+	backfillAddresses := backfillCmd.String("address", "", "Comma-separated addresses to backfill")
+	fromBlock := backfillCmd.Int64("from", 0, "First block of the range")
+	toBlock := backfillCmd.Int64("to", 0, "Last block of the range")
+	jobID := backfillCmd.String("job", "", "ID of an interrupted backfill job to resume")
+	backfillCmd.StringVar(&cfg.EthereumRPCURL, "rpc-url", cfg.EthereumRPCURL, "Ethereum RPC URL")
+	backfillCmd.Var(&cfg.RPCEndpoints, "rpc-urls", "Comma-separated pool of RPC URLs, each optionally suffixed with |weight; overrides --rpc-url")
+	backfillCmd.IntVar(&cfg.Quorum, "quorum", cfg.Quorum, "Number of RPC endpoints that must agree on a block before it is indexed; 0 disables")
+	backfillCmd.Float64Var(&cfg.RPCRateLimit, "rpc-rate-limit", cfg.RPCRateLimit, "Maximum average calls per second to each RPC endpoint; 0 disables")
+	backfillCmd.IntVar(&cfg.RPCRateBurst, "rpc-rate-burst", cfg.RPCRateBurst, "Calls that may be sent to an RPC endpoint at once under --rpc-rate-limit")
+	backfillCmd.StringVar(&cfg.ConfirmationPolicy, "confirmation-policy", cfg.ConfirmationPolicy, "Blocks to index (latest, confirmations, safe, finalized)")
+	backfillCmd.IntVar(&cfg.Confirmations, "confirmations", cfg.Confirmations, "Confirmations required before a transaction is confirmed")
+	backfillCmd.StringVar(&cfg.StorageBackend, "storage", cfg.StorageBackend, "Storage backend (memory, disk)")
+	backfillCmd.StringVar(&cfg.DataDir, "data-dir", cfg.DataDir, "Data directory for the disk storage backend")
+	backfillCmd.IntVar(&cfg.FetchWorkers, "fetch-workers", cfg.FetchWorkers, "Number of blocks fetched concurrently")
+	backfillCmd.StringVar(&cfg.TraceMode, "trace-mode", cfg.TraceMode, "Index internal transfers from call traces (debug, trace); empty disables")
+
+	// Define flags for the "abi register" subcommand
+	abiAddress := abiRegisterCmd.String("address", "", "Contract address")
+	abiFile := abiRegisterCmd.String("file", "", "Path to the contract's JSON ABI")
+	abiRegisterCmd.StringVar(&cfg.ABIDir, "abi-dir", cfg.ABIDir, "Directory of contract ABIs")
+
 	// Parse the top-level command
 	if len(os.Args) < 2 {
-		fmt.Println("Expected 'start', 'send', or 'create_key' subcommands")
+		fmt.Println("Expected 'start', 'send', 'backfill', 'abi', or 'create_key' subcommands")
 		return
 	}
 
@@ -54,11 +98,23 @@ func main() {
 		sendCmd.Parse(os.Args[2:])
 		handleSend(*privateKey, *toAddress, *value, cfg.EthereumRPCURL)
 
+	case "backfill":
+		backfillCmd.Parse(os.Args[2:])
+		handleBackfill(cfg, *backfillAddresses, *fromBlock, *toBlock, *jobID)
+
+	case "abi":
+		if len(os.Args) < 3 || os.Args[2] != "register" {
+			fmt.Println("Expected 'abi register' subcommand")
+			return
+		}
+		abiRegisterCmd.Parse(os.Args[3:])
+		handleABIRegister(cfg, *abiAddress, *abiFile)
+
 	case "create_key":
 		createKeyCmd.Parse(os.Args[2:])
 		handleCreateKey()
 
 	default:
-		fmt.Println("Unknown command. Expected 'start', 'send', or 'create_key'")
+		fmt.Println("Unknown command. Expected 'start', 'send', 'backfill', 'abi', or 'create_key'")
 	}
 }
@@ -1,18 +1,24 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/ethereum_parser/internal/api"
 	"github.com/ethereum_parser/internal/config"
 	"github.com/ethereum_parser/internal/parser"
 	"github.com/ethereum_parser/internal/storage"
-	"github.com/ethereum_parser/internal/types"
 )
 
+// shutdownTimeout bounds how long in-flight HTTP requests may take to finish
+const shutdownTimeout = 10 * time.Second
+
 func handleStart(cfg *config.Config) {
 
 	flag.StringVar(&cfg.EthereumRPCURL, "rpc-url", cfg.EthereumRPCURL, "Ethereum RPC endpoint URL")
@@ -25,21 +31,73 @@ func handleStart(cfg *config.Config) {
 
 	setupLogging(cfg.LogLevel)
 
-	// Initialize in-memory storage
-	memoryStore := storage.NewMemoryStorage()
+	store, err := openStorage(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	defer store.Close()
 
-	ethParser, err := parser.NewEthereumParser(memoryStore, cfg)
+	ethParser, err := parser.NewEthereumParser(store, cfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize parser: %v", err)
 	}
 
 	log.Printf("Starting Ethereum Transaction Parser")
-	log.Printf("RPC URL: %s", cfg.EthereumRPCURL)
 	log.Printf("HTTP Port: %d", cfg.HTTPPort)
+	log.Printf("Storage: %s", cfg.StorageBackend)
+	log.Printf("Poll interval: %s, poll timeout: %s", cfg.PollInterval, cfg.PollTimeout)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start block polling
+	if err := ethParser.Start(ctx); err != nil {
+		log.Fatalf("Failed to start block polling: %v", err)
+	}
 
 	// Start HTTP server
-	if err := startHTTPServer(ethParser, cfg.HTTPPort); err != nil {
-		log.Fatalf("Failed to start HTTP server: %v", err)
+	server := api.NewHTTPServer(ethParser)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- startHTTPServer(server, cfg.HTTPPort)
+	}()
+
+	select {
+	case <-ctx.Done():
+		log.Printf("Shutdown signal received")
+	case err := <-serverErr:
+		ethParser.Stop()
+		if err != nil {
+			log.Fatalf("Failed to start HTTP server: %v", err)
+		}
+		return
+	}
+
+	// Let the poller finish the block it is working on before the API goes away
+	ethParser.Stop()
+	log.Printf("Block polling stopped")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to shut down HTTP server: %v", err)
+	}
+	if err := <-serverErr; err != nil {
+		log.Printf("HTTP server error: %v", err)
+	}
+	log.Printf("Shutdown complete")
+}
+
+// openStorage creates the storage backend selected in the configuration
+func openStorage(cfg *config.Config) (storage.Storage, error) {
+	switch cfg.StorageBackend {
+	case "memory":
+		return storage.NewMemoryStorage(), nil
+	case "disk":
+		return storage.NewDiskStorage(cfg.DataDir)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q (expected memory or disk)", cfg.StorageBackend)
 	}
 }
 
@@ -55,9 +113,8 @@ func setupLogging(level string) {
 	}
 }
 
-func startHTTPServer(parser types.Parser, port int) error {
+func startHTTPServer(server *api.HTTPServer, port int) error {
 	// Implement HTTP server startup with configurable port
-	server := api.NewHTTPServer(parser)
 	serverAddr := fmt.Sprintf(":%d", port)
 	log.Printf("Starting HTTP server on %s", serverAddr)
 	return server.Start(serverAddr)
@@ -1,28 +1,55 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 
+	"github.com/ethereum_parser/internal/ethereum"
 	"github.com/ethereum_parser/internal/types"
 )
 
 type HTTPServer struct {
 	parser types.Parser
+	server *http.Server
 }
 
 func NewHTTPServer(p types.Parser) *HTTPServer {
-	return &HTTPServer{parser: p}
+	s := &HTTPServer{parser: p}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/subscribe", s.handleSubscribe)
+	mux.HandleFunc("/transactions", s.handleGetTransactions)
+	mux.HandleFunc("/pending-transactions", s.handleGetPendingTransactions)
+	mux.HandleFunc("/token-transfers", s.handleGetTokenTransfers)
+	mux.HandleFunc("/internal-transfers", s.handleGetInternalTransfers)
+	mux.HandleFunc("/current-block", s.handleGetCurrentBlock)
+	mux.HandleFunc("/backfill", s.handleBackfill)
+	mux.HandleFunc("/disagreements", s.handleGetDisagreements)
+
+	s.server = &http.Server{Handler: mux}
+	return s
 }
 
+// Start serves the API on addr and blocks until the server is shut down.
 func (s *HTTPServer) Start(addr string) error {
-	http.HandleFunc("/subscribe", s.handleSubscribe)
-	http.HandleFunc("/transactions", s.handleGetTransactions)
-	http.HandleFunc("/current-block", s.handleGetCurrentBlock)
+	s.server.Addr = addr
 
 	log.Printf("Starting HTTP server on %s", addr)
-	return http.ListenAndServe(addr, nil)
+	if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown gracefully stops the server, waiting for active requests until
+// ctx expires.
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
 }
 
 // subscribe the address
@@ -35,34 +62,286 @@ func (s *HTTPServer) handleSubscribe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	success := s.parser.Subscribe(req.Address)
+	address, err := types.ParseAddress(req.Address)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	success := s.parser.Subscribe(r.Context(), address)
 	json.NewEncoder(w).Encode(map[string]bool{"success": success})
 }
 
 // get transction for given address
 func (s *HTTPServer) handleGetTransactions(w http.ResponseWriter, r *http.Request) {
-	address := r.URL.Query().Get("address")
-	if address == "" {
+	if r.URL.Query().Get("address") == "" {
 		http.Error(w, "address is required", http.StatusBadRequest)
 		return
 	}
 
-	txs, err := s.parser.GetTransactions(address)
+	address, err := types.ParseAddress(r.URL.Query().Get("address"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status := types.ConfirmationStatus(r.URL.Query().Get("status"))
+	if status != "" && !status.IsValid() {
+		http.Error(w, "status must be one of pending-confirmation, confirmed, finalized", http.StatusBadRequest)
+		return
+	}
+
+	txs, err := s.parser.GetTransactions(r.Context(), address)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
+	if status != "" {
+		filtered := make([]types.Transaction, 0, len(txs))
+		for _, tx := range txs {
+			if tx.Confirmation == status {
+				filtered = append(filtered, tx)
+			}
+		}
+		txs = filtered
+	}
+
 	json.NewEncoder(w).Encode(txs)
 }
 
+// get mempool transactions for given address
+func (s *HTTPServer) handleGetPendingTransactions(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("address") == "" {
+		http.Error(w, "address is required", http.StatusBadRequest)
+		return
+	}
+
+	address, err := types.ParseAddress(r.URL.Query().Get("address"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status := types.PendingStatus(r.URL.Query().Get("status"))
+	switch status {
+	case "", types.PendingStatusPending, types.PendingStatusMined, types.PendingStatusReplaced, types.PendingStatusDropped:
+	default:
+		http.Error(w, "invalid status", http.StatusBadRequest)
+		return
+	}
+
+	txs, err := s.parser.GetPendingTransactions(r.Context(), address)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if status != "" {
+		filtered := make([]types.PendingTransaction, 0, len(txs))
+		for _, tx := range txs {
+			if tx.Status == status {
+				filtered = append(filtered, tx)
+			}
+		}
+		txs = filtered
+	}
+
+	json.NewEncoder(w).Encode(txs)
+}
+
+// get token transfers for given address
+func (s *HTTPServer) handleGetTokenTransfers(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("address") == "" {
+		http.Error(w, "address is required", http.StatusBadRequest)
+		return
+	}
+
+	address, err := types.ParseAddress(r.URL.Query().Get("address"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var token types.Address
+	if tokenParam := r.URL.Query().Get("token"); tokenParam != "" {
+		if token, err = types.ParseAddress(tokenParam); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	standard := types.TokenStandard(r.URL.Query().Get("standard"))
+	switch standard {
+	case "", types.StandardERC20, types.StandardERC721, types.StandardERC1155:
+	default:
+		http.Error(w, "invalid standard", http.StatusBadRequest)
+		return
+	}
+
+	transfers, err := s.parser.GetTokenTransfers(r.Context(), address)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if token != "" || standard != "" {
+		filtered := make([]types.TokenTransfer, 0, len(transfers))
+		for _, transfer := range transfers {
+			if (token == "" || transfer.Token == token) && (standard == "" || transfer.Standard == standard) {
+				filtered = append(filtered, transfer)
+			}
+		}
+		transfers = filtered
+	}
+
+	json.NewEncoder(w).Encode(transfers)
+}
+
+// get internal transfers for given address
+func (s *HTTPServer) handleGetInternalTransfers(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("address") == "" {
+		http.Error(w, "address is required", http.StatusBadRequest)
+		return
+	}
+
+	address, err := types.ParseAddress(r.URL.Query().Get("address"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	transfers, err := s.parser.GetInternalTransfers(r.Context(), address)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(transfers)
+}
+
 // get the current block
 func (s *HTTPServer) handleGetCurrentBlock(w http.ResponseWriter, r *http.Request) {
-	block, err := s.parser.GetCurrentBlock()
+	block, err := s.parser.GetCurrentBlock(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]int64{"block": block})
 }
+
+// start a backfill job or get its progress
+func (s *HTTPServer) handleBackfill(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		var req struct {
+			Addresses []string `json:"addresses"`
+			From      int64    `json:"from"`
+			To        int64    `json:"to"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		addresses := make([]types.Address, 0, len(req.Addresses))
+		for _, a := range req.Addresses {
+			address, err := types.ParseAddress(a)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			addresses = append(addresses, address)
+		}
+
+		job, err := s.parser.StartBackfill(r.Context(), addresses, req.From, req.To)
+		if err != nil {
+			writeError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(job)
+
+	case http.MethodGet:
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "id is required", http.StatusBadRequest)
+			return
+		}
+
+		job, err := s.parser.GetBackfillJob(r.Context(), id)
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+		if job == nil {
+			http.Error(w, "backfill job not found", http.StatusNotFound)
+			return
+		}
+
+		json.NewEncoder(w).Encode(job)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// get the disagreements between RPC providers found in quorum mode,
+// optionally for a single block
+func (s *HTTPServer) handleGetDisagreements(w http.ResponseWriter, r *http.Request) {
+	var blockNumber int64 = -1
+	if blockStr := r.URL.Query().Get("block"); blockStr != "" {
+		n, err := strconv.ParseInt(blockStr, 10, 64)
+		if err != nil || n < 0 {
+			http.Error(w, "block must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		blockNumber = n
+	}
+
+	disagreements, err := s.parser.GetDisagreements(r.Context())
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if blockNumber >= 0 {
+		filtered := make([]types.Disagreement, 0, len(disagreements))
+		for _, d := range disagreements {
+			if d.BlockNumber == blockNumber {
+				filtered = append(filtered, d)
+			}
+		}
+		disagreements = filtered
+	}
+
+	json.NewEncoder(w).Encode(disagreements)
+}
+
+// writeError responds with the status matching an error of the Ethereum
+// node, or with status for any other error
+func writeError(w http.ResponseWriter, err error, status int) {
+	var httpErr *ethereum.HTTPError
+	var rpcErr *ethereum.RPCError
+
+	switch {
+	case errors.Is(err, ethereum.ErrRateLimited):
+		if errors.As(err, &httpErr) && httpErr.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(httpErr.RetryAfter.Seconds()))))
+		}
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, ethereum.ErrBlockNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ethereum.ErrMethodNotFound):
+		status = http.StatusNotImplemented
+	case errors.Is(err, ethereum.ErrExecutionReverted):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+	case errors.As(err, &rpcErr), errors.As(err, &httpErr):
+		status = http.StatusBadGateway
+	}
+	http.Error(w, err.Error(), status)
+}
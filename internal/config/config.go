@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"time"
 )
 
 // Config holds application configuration
@@ -11,6 +12,63 @@ type Config struct {
 	HTTPPort       int
 	LogLevel       string
 	WebhookURL     string
+	PollInterval   time.Duration
+	PollTimeout    time.Duration
+	StorageBackend string
+	DataDir        string
+
+	// CatchupBatchSize bounds the number of blocks processed per poll cycle
+	CatchupBatchSize int
+	// ReorgWindow is the number of recent block hashes kept for reorg detection
+	ReorgWindow int
+	// ConfirmationPolicy selects which blocks are indexed: latest,
+	// confirmations, safe or finalized
+	ConfirmationPolicy string
+	// Confirmations is the number of blocks on top of a block before its
+	// transactions are considered confirmed
+	Confirmations int
+	// FetchWorkers is the number of blocks fetched concurrently
+	FetchWorkers int
+	// TraceMode enables internal transfer indexing through call traces:
+	// "debug" (debug_traceBlockByNumber) or "trace" (trace_block). Empty
+	// disables tracing.
+	TraceMode string
+	// ABIDir holds the contract ABIs used to decode transaction input
+	ABIDir string
+	// MempoolWatch enables tracking of pending transactions
+	MempoolWatch bool
+	// MempoolInterval is the interval between mempool filter polls
+	MempoolInterval time.Duration
+	// PendingDropTimeout is how long a pending transaction may stay unmined
+	// before the node is asked whether it still knows it
+	PendingDropTimeout time.Duration
+	// RPCEndpoints replaces EthereumRPCURL with a pool of weighted
+	// endpoints when set
+	RPCEndpoints RPCEndpoints
+	// MaxHeadLag is how many blocks an endpoint of the pool may trail the
+	// highest observed head before it is ejected
+	MaxHeadLag int
+	// ProviderProbation is how long an ejected endpoint is kept out of
+	// rotation before it is checked again
+	ProviderProbation time.Duration
+	// Quorum is the number of endpoints of the pool that must agree on a
+	// block and its matched transactions before they are stored. Values
+	// below 2 disable quorum verification.
+	Quorum int
+	// RPCRateLimit caps the average number of calls per second sent to
+	// each RPC endpoint, in bursts of up to RPCRateBurst calls. Zero
+	// disables the limit.
+	RPCRateLimit float64
+	RPCRateBurst int
+}
+
+// Endpoints returns the RPC endpoints to connect to: the pool if one is
+// configured, otherwise EthereumRPCURL alone
+func (c *Config) Endpoints() RPCEndpoints {
+	if len(c.RPCEndpoints) > 0 {
+		return c.RPCEndpoints
+	}
+	return RPCEndpoints{{URL: c.EthereumRPCURL, Weight: 1}}
 }
 
 // NewConfig creates a default configuration
@@ -20,6 +78,26 @@ func NewConfig() *Config {
 		HTTPPort:       8060,
 		LogLevel:       "info",
 		WebhookURL:     "https://example-webhook-url.com/notify", // Default webhook URL
+		PollInterval:   15 * time.Second,
+		PollTimeout:    10 * time.Second,
+		StorageBackend: "memory",
+		DataDir:        "data",
+
+		CatchupBatchSize: 50,
+		ReorgWindow:      64,
+
+		ConfirmationPolicy: "latest",
+		Confirmations:      12,
+		FetchWorkers:       4,
+		ABIDir:             "abis",
+
+		MempoolInterval:    2 * time.Second,
+		PendingDropTimeout: 30 * time.Minute,
+
+		MaxHeadLag:        5,
+		ProviderProbation: time.Minute,
+
+		RPCRateBurst: 10,
 	}
 }
 
@@ -43,4 +121,115 @@ func (c *Config) LoadEnvironmentVariables() {
 	if webhookURL := os.Getenv("WEBHOOK_URL"); webhookURL != "" {
 		c.WebhookURL = webhookURL
 	}
+
+	if intervalStr := os.Getenv("POLL_INTERVAL"); intervalStr != "" {
+		if interval, err := time.ParseDuration(intervalStr); err == nil {
+			c.PollInterval = interval
+		}
+	}
+
+	if timeoutStr := os.Getenv("POLL_TIMEOUT"); timeoutStr != "" {
+		if timeout, err := time.ParseDuration(timeoutStr); err == nil {
+			c.PollTimeout = timeout
+		}
+	}
+
+	if backend := os.Getenv("STORAGE_BACKEND"); backend != "" {
+		c.StorageBackend = backend
+	}
+
+	if dataDir := os.Getenv("DATA_DIR"); dataDir != "" {
+		c.DataDir = dataDir
+	}
+
+	if batchStr := os.Getenv("CATCHUP_BATCH_SIZE"); batchStr != "" {
+		if batch, err := strconv.Atoi(batchStr); err == nil && batch > 0 {
+			c.CatchupBatchSize = batch
+		}
+	}
+
+	if windowStr := os.Getenv("REORG_WINDOW"); windowStr != "" {
+		if window, err := strconv.Atoi(windowStr); err == nil && window > 0 {
+			c.ReorgWindow = window
+		}
+	}
+
+	if policy := os.Getenv("CONFIRMATION_POLICY"); policy != "" {
+		c.ConfirmationPolicy = policy
+	}
+
+	if confirmationsStr := os.Getenv("CONFIRMATIONS"); confirmationsStr != "" {
+		if confirmations, err := strconv.Atoi(confirmationsStr); err == nil && confirmations >= 0 {
+			c.Confirmations = confirmations
+		}
+	}
+
+	if workersStr := os.Getenv("FETCH_WORKERS"); workersStr != "" {
+		if workers, err := strconv.Atoi(workersStr); err == nil && workers > 0 {
+			c.FetchWorkers = workers
+		}
+	}
+
+	if traceMode := os.Getenv("TRACE_MODE"); traceMode != "" {
+		c.TraceMode = traceMode
+	}
+
+	if abiDir := os.Getenv("ABI_DIR"); abiDir != "" {
+		c.ABIDir = abiDir
+	}
+
+	if watchStr := os.Getenv("MEMPOOL_WATCH"); watchStr != "" {
+		if watch, err := strconv.ParseBool(watchStr); err == nil {
+			c.MempoolWatch = watch
+		}
+	}
+
+	if intervalStr := os.Getenv("MEMPOOL_INTERVAL"); intervalStr != "" {
+		if interval, err := time.ParseDuration(intervalStr); err == nil && interval > 0 {
+			c.MempoolInterval = interval
+		}
+	}
+
+	if timeoutStr := os.Getenv("PENDING_DROP_TIMEOUT"); timeoutStr != "" {
+		if timeout, err := time.ParseDuration(timeoutStr); err == nil && timeout > 0 {
+			c.PendingDropTimeout = timeout
+		}
+	}
+
+	if rpcURLs := os.Getenv("ETHEREUM_RPC_URLS"); rpcURLs != "" {
+		var endpoints RPCEndpoints
+		if err := endpoints.Set(rpcURLs); err == nil {
+			c.RPCEndpoints = endpoints
+		}
+	}
+
+	if lagStr := os.Getenv("MAX_HEAD_LAG"); lagStr != "" {
+		if lag, err := strconv.Atoi(lagStr); err == nil && lag >= 0 {
+			c.MaxHeadLag = lag
+		}
+	}
+
+	if probationStr := os.Getenv("PROVIDER_PROBATION"); probationStr != "" {
+		if probation, err := time.ParseDuration(probationStr); err == nil && probation > 0 {
+			c.ProviderProbation = probation
+		}
+	}
+
+	if quorumStr := os.Getenv("RPC_QUORUM"); quorumStr != "" {
+		if quorum, err := strconv.Atoi(quorumStr); err == nil && quorum >= 0 {
+			c.Quorum = quorum
+		}
+	}
+
+	if rateStr := os.Getenv("RPC_RATE_LIMIT"); rateStr != "" {
+		if rate, err := strconv.ParseFloat(rateStr, 64); err == nil && rate >= 0 {
+			c.RPCRateLimit = rate
+		}
+	}
+
+	if burstStr := os.Getenv("RPC_RATE_BURST"); burstStr != "" {
+		if burst, err := strconv.Atoi(burstStr); err == nil && burst > 0 {
+			c.RPCRateBurst = burst
+		}
+	}
 }
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RPCEndpoint is an RPC endpoint of the provider pool. Weight scales how
+// strongly the endpoint is preferred over equally healthy ones.
+type RPCEndpoint struct {
+	URL    string
+	Weight int
+}
+
+// RPCEndpoints is a list of RPC endpoints written as comma-separated URLs,
+// each optionally followed by |weight, e.g.
+// "https://a.example|3,https://b.example". It implements flag.Value.
+type RPCEndpoints []RPCEndpoint
+
+func (e RPCEndpoints) String() string {
+	parts := make([]string, len(e))
+	for i, endpoint := range e {
+		parts[i] = fmt.Sprintf("%s|%d", endpoint.URL, endpoint.Weight)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Set replaces the list with the endpoints parsed from value
+func (e *RPCEndpoints) Set(value string) error {
+	var endpoints RPCEndpoints
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		endpoint := RPCEndpoint{URL: part, Weight: 1}
+		if url, weightStr, ok := strings.Cut(part, "|"); ok {
+			weight, err := strconv.Atoi(weightStr)
+			if err != nil || weight <= 0 {
+				return fmt.Errorf("invalid weight %q for RPC endpoint %s", weightStr, url)
+			}
+			endpoint = RPCEndpoint{URL: url, Weight: weight}
+		}
+		if endpoint.URL == "" {
+			return fmt.Errorf("empty RPC endpoint URL in %q", value)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	*e = endpoints
+	return nil
+}
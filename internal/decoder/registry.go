@@ -0,0 +1,150 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+const abiExt = ".json"
+
+// Registry decodes contract call input data using JSON ABIs registered per
+// contract address, falling back to a bundled table of common function
+// selectors. ABIs are stored in a directory as <address>.json.
+type Registry struct {
+	dir string
+
+	mu   sync.RWMutex
+	abis map[types.Address]*abi.ABI
+}
+
+// NewRegistry loads every ABI stored in dir. A missing directory yields an
+// empty registry; it is created on the first Register call.
+func NewRegistry(dir string) (*Registry, error) {
+	r := &Registry{
+		dir:  dir,
+		abis: make(map[types.Address]*abi.ABI),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ABI directory: %v", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != abiExt {
+			continue
+		}
+
+		address, err := types.ParseAddress(strings.TrimSuffix(name, abiExt))
+		if err != nil {
+			log.Printf("Skipping ABI file %s: %v", name, err)
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ABI file %s: %v", name, err)
+		}
+		parsed, err := abi.JSON(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ABI file %s: %v", name, err)
+		}
+		r.abis[address] = &parsed
+	}
+
+	return r, nil
+}
+
+// Register validates abiJSON and stores it as the ABI of the contract at
+// address, replacing any previously registered ABI
+func (r *Registry) Register(address types.Address, abiJSON []byte) error {
+	parsed, err := abi.JSON(bytes.NewReader(abiJSON))
+	if err != nil {
+		return fmt.Errorf("invalid ABI: %v", err)
+	}
+
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create ABI directory: %v", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a partial ABI
+	path := filepath.Join(r.dir, address.String()+abiExt)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, abiJSON, 0o644); err != nil {
+		return fmt.Errorf("failed to write ABI file: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write ABI file: %v", err)
+	}
+
+	r.mu.Lock()
+	r.abis[address] = &parsed
+	r.mu.Unlock()
+	return nil
+}
+
+// Decode decodes the input data of a call to the contract at address. It
+// returns nil for plain transfers and for calls whose selector is neither in
+// the contract's ABI nor in the bundled selector table.
+func (r *Registry) Decode(address types.Address, input string) *types.DecodedCall {
+	data, err := hex.DecodeString(strings.TrimPrefix(input, "0x"))
+	if err != nil || len(data) < 4 {
+		return nil
+	}
+
+	r.mu.RLock()
+	contract := r.abis[address]
+	r.mu.RUnlock()
+
+	if contract != nil {
+		if method, err := contract.MethodById(data[:4]); err == nil {
+			return decodeCall(method.RawName, method.Sig, method.Inputs, data[4:])
+		}
+	}
+
+	if method, ok := commonSelectors[string(data[:4])]; ok {
+		return decodeCall(method.name, method.signature, method.inputs, data[4:])
+	}
+	return nil
+}
+
+// decodeCall unpacks the arguments of a call. Arguments that do not match
+// the signature are reported through the Error field rather than dropping
+// the method name.
+func decodeCall(name, signature string, inputs abi.Arguments, data []byte) *types.DecodedCall {
+	call := &types.DecodedCall{Method: name, Signature: signature}
+
+	values, err := inputs.Unpack(data)
+	if err != nil {
+		call.Error = err.Error()
+		return call
+	}
+
+	call.Args = make([]types.DecodedArg, len(inputs))
+	for i, input := range inputs {
+		argName := input.Name
+		if argName == "" {
+			argName = fmt.Sprintf("arg%d", i)
+		}
+		call.Args[i] = types.DecodedArg{
+			Name:  argName,
+			Type:  input.Type.String(),
+			Value: formatValue(values[i]),
+		}
+	}
+	return call
+}
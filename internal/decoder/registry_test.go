@@ -0,0 +1,78 @@
+package decoder
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+const tokenABI = `[{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"type":"bool"}]}]`
+
+// transferInput is transfer(0x...01, 1000)
+var transferInput = "0xa9059cbb" + fmt.Sprintf("%064x", 1) + fmt.Sprintf("%064x", 1000)
+
+func TestRegistryDecodesRegisteredABI(t *testing.T) {
+	dir := t.TempDir()
+	token := types.Address(fmt.Sprintf("0x%040x", 99))
+
+	registry, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := registry.Register(token, []byte(tokenABI)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Registered ABIs are loaded again on startup
+	registry, err = NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	call := registry.Decode(token, transferInput)
+	if call == nil {
+		t.Fatalf("Expected decoded call")
+	}
+	if call.Method != "transfer" || len(call.Args) != 2 {
+		t.Fatalf("Unexpected call: %+v", call)
+	}
+	if call.Args[0].Name != "to" || call.Args[0].Value != types.Address(fmt.Sprintf("0x%040x", 1)) {
+		t.Errorf("Unexpected first argument: %+v", call.Args[0])
+	}
+	if call.Args[1].Name != "amount" || call.Args[1].Type != "uint256" || call.Args[1].Value != "1000" {
+		t.Errorf("Unexpected second argument: %+v", call.Args[1])
+	}
+
+	if err := registry.Register(token, []byte(`{"not":"an abi"}`)); err == nil {
+		t.Errorf("Expected error for invalid ABI")
+	}
+}
+
+func TestRegistryFallsBackToCommonSelectors(t *testing.T) {
+	registry, err := NewRegistry(t.TempDir())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	unknown := types.Address(fmt.Sprintf("0x%040x", 7))
+
+	call := registry.Decode(unknown, transferInput)
+	if call == nil || call.Signature != "transfer(address,uint256)" {
+		t.Fatalf("Unexpected call: %+v", call)
+	}
+	if call.Args[0].Name != "arg0" || call.Args[1].Value != "1000" {
+		t.Errorf("Unexpected arguments: %+v", call.Args)
+	}
+
+	// Truncated arguments keep the method but report the error
+	call = registry.Decode(unknown, transferInput[:20])
+	if call == nil || call.Error == "" || call.Args != nil {
+		t.Errorf("Expected decoding error, got %+v", call)
+	}
+
+	for _, input := range []string{"0x", "0xdeadbeef", "not hex"} {
+		if call := registry.Decode(unknown, input); call != nil {
+			t.Errorf("Expected no decoded call for %s, got %+v", input, call)
+		}
+	}
+}
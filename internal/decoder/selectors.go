@@ -0,0 +1,86 @@
+package decoder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// commonSignatures are the functions decoded for contracts without a
+// registered ABI: token standards, WETH and the most used router methods
+var commonSignatures = []string{
+	// ERC-20
+	"transfer(address,uint256)",
+	"transferFrom(address,address,uint256)",
+	"approve(address,uint256)",
+	"increaseAllowance(address,uint256)",
+	"decreaseAllowance(address,uint256)",
+	"mint(address,uint256)",
+	"burn(uint256)",
+	// ERC-721 and ERC-1155
+	"safeTransferFrom(address,address,uint256)",
+	"safeTransferFrom(address,address,uint256,bytes)",
+	"setApprovalForAll(address,bool)",
+	"safeTransferFrom(address,address,uint256,uint256,bytes)",
+	"safeBatchTransferFrom(address,address,uint256[],uint256[],bytes)",
+	// WETH
+	"deposit()",
+	"withdraw(uint256)",
+	// Routers
+	"multicall(bytes[])",
+	"multicall(uint256,bytes[])",
+	"execute(bytes,bytes[],uint256)",
+	"swapExactETHForTokens(uint256,address[],address,uint256)",
+	"swapExactTokensForETH(uint256,uint256,address[],address,uint256)",
+	"swapExactTokensForTokens(uint256,uint256,address[],address,uint256)",
+	"swapETHForExactTokens(uint256,address[],address,uint256)",
+	"swapTokensForExactTokens(uint256,uint256,address[],address,uint256)",
+}
+
+// selectorMethod is a function known only by its signature
+type selectorMethod struct {
+	name      string
+	signature string
+	inputs    abi.Arguments
+}
+
+// commonSelectors maps 4-byte selectors to the methods of commonSignatures
+var commonSelectors = buildSelectors(commonSignatures)
+
+func buildSelectors(signatures []string) map[string]selectorMethod {
+	selectors := make(map[string]selectorMethod, len(signatures))
+	for _, signature := range signatures {
+		method, err := parseSignature(signature)
+		if err != nil {
+			panic(fmt.Sprintf("invalid bundled signature %s: %v", signature, err))
+		}
+		selectors[string(crypto.Keccak256([]byte(signature))[:4])] = method
+	}
+	return selectors
+}
+
+// parseSignature parses a canonical signature such as
+// "transfer(address,uint256)" whose arguments are not tuples
+func parseSignature(signature string) (selectorMethod, error) {
+	open := strings.IndexByte(signature, '(')
+	if open <= 0 || !strings.HasSuffix(signature, ")") {
+		return selectorMethod{}, fmt.Errorf("malformed signature")
+	}
+
+	method := selectorMethod{name: signature[:open], signature: signature}
+	params := signature[open+1 : len(signature)-1]
+	if params == "" {
+		return method, nil
+	}
+
+	for _, param := range strings.Split(params, ",") {
+		typ, err := abi.NewType(param, "", nil)
+		if err != nil {
+			return selectorMethod{}, err
+		}
+		method.inputs = append(method.inputs, abi.Argument{Type: typ})
+	}
+	return method, nil
+}
@@ -0,0 +1,226 @@
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// defaultBatchSize is the largest batch sent until the node rejects one
+const defaultBatchSize = 100
+
+// errBatchTooLarge is returned when the node refuses a batch because of its
+// size
+var errBatchTooLarge = errors.New("batch too large")
+
+// BatchElem is a single call in a batch request
+type BatchElem struct {
+	Method string
+	Params []interface{}
+	// Result receives the decoded result of the call; it must be a pointer
+	// or nil to discard the result
+	Result interface{}
+	// Error is set when the call failed
+	Error error
+}
+
+// BatchCall sends calls to the node in as few JSON-RPC batch requests as
+// possible. Batches the node rejects as too large are split, and later
+// batches are kept at the smaller size. The returned error only reports
+// batches that could not be delivered; failures of single calls are
+// reported in their Error field.
+func (c *Client) BatchCall(ctx context.Context, calls []BatchElem) error {
+	for start := 0; start < len(calls); {
+		size := c.batchSize.Load()
+		end := min(start+int(size), len(calls))
+
+		err := c.sendBatch(ctx, calls[start:end])
+		if errors.Is(err, errBatchTooLarge) && end-start > 1 {
+			smaller := int64(max((end-start)/2, 1))
+			log.Printf("RPC endpoint rejected a batch of %d calls, retrying with batches of %d", end-start, smaller)
+			c.lowerBatchSize(smaller)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// lowerBatchSize caps the batch size at size
+func (c *Client) lowerBatchSize(size int64) {
+	for {
+		current := c.batchSize.Load()
+		if current <= size || c.batchSize.CompareAndSwap(current, size) {
+			return
+		}
+	}
+}
+
+// sendBatch sends calls as a single batch and matches the responses to
+// them by request ID
+func (c *Client) sendBatch(ctx context.Context, calls []BatchElem) error {
+	reqs := make([]*JSONRPCRequest, len(calls))
+	byID := make(map[int]*BatchElem, len(calls))
+	for i := range calls {
+		reqs[i] = c.newRequest(calls[i].Method, calls[i].Params)
+		byID[reqs[i].ID] = &calls[i]
+		calls[i].Error = nil
+	}
+
+	var resps []*JSONRPCResponse
+	err := c.retry(ctx, "batch request", func() error {
+		var err error
+		resps, err = c.transport.batchRoundTrip(ctx, reqs)
+		if err != nil {
+			return err
+		}
+		// A node over its rate limit may refuse the whole batch at once
+		if len(reqs) > 1 && len(resps) == 1 && resps[0].Error != nil && errors.Is(resps[0].Error, ErrRateLimited) {
+			return rpcError("batch request", resps[0].Error)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if batchRejected(resps, len(reqs)) {
+		return errBatchTooLarge
+	}
+
+	answered := make(map[int]bool, len(resps))
+	for _, resp := range resps {
+		call, ok := byID[resp.ID]
+		if !ok || answered[resp.ID] {
+			continue
+		}
+		answered[resp.ID] = true
+
+		switch {
+		case resp.Error != nil:
+			call.Error = rpcError(call.Method, resp.Error)
+		case call.Result != nil:
+			if err := json.Unmarshal(resp.Result, call.Result); err != nil {
+				call.Error = fmt.Errorf("failed to parse %s result: %v", call.Method, err)
+			}
+		}
+	}
+
+	for _, req := range reqs {
+		if !answered[req.ID] {
+			byID[req.ID].Error = fmt.Errorf("no response to %s in batch", req.Method)
+		}
+	}
+	return nil
+}
+
+// batchRejected reports whether the node answered a batch of n calls with
+// a size limit error instead of their responses. Providers word the error
+// differently, but all of them mention the batch.
+func batchRejected(resps []*JSONRPCResponse, n int) bool {
+	if len(resps) >= n {
+		return false
+	}
+	for _, resp := range resps {
+		if resp.Error != nil && strings.Contains(strings.ToLower(resp.Error.Message), "batch") {
+			return true
+		}
+	}
+	return false
+}
+
+// isBatchResponse reports whether data is a JSON array of responses
+func isBatchResponse(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '['
+}
+
+// parseBatchResponse decodes the response to a batch. Nodes that refuse
+// the whole batch may answer with a single error object instead of an
+// array.
+func parseBatchResponse(data []byte) ([]*JSONRPCResponse, error) {
+	if !isBatchResponse(data) {
+		var resp JSONRPCResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON-RPC batch response: %v", err)
+		}
+		return []*JSONRPCResponse{&resp}, nil
+	}
+
+	var resps []*JSONRPCResponse
+	if err := json.Unmarshal(data, &resps); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON-RPC batch response: %v", err)
+	}
+	return resps, nil
+}
+
+// GetBlocks retrieves several blocks with their transactions in batch
+// requests, returned in the order of blockNumbers
+func (c *Client) GetBlocks(ctx context.Context, blockNumbers []int64) ([]*types.Block, error) {
+	raw := make([]rpcBlock, len(blockNumbers))
+	calls := make([]BatchElem, len(blockNumbers))
+	for i, number := range blockNumbers {
+		calls[i] = BatchElem{
+			Method: "eth_getBlockByNumber",
+			Params: []interface{}{fmt.Sprintf("0x%x", number), true},
+			Result: &raw[i],
+		}
+	}
+
+	if err := c.BatchCall(ctx, calls); err != nil {
+		return nil, fmt.Errorf("failed to fetch blocks: %w", err)
+	}
+
+	blocks := make([]*types.Block, len(blockNumbers))
+	for i, number := range blockNumbers {
+		if calls[i].Error != nil {
+			return nil, fmt.Errorf("failed to fetch block %d: %w", number, calls[i].Error)
+		}
+		block, err := raw[i].toBlock(number)
+		if err != nil {
+			return nil, err
+		}
+		blocks[i] = block
+	}
+	return blocks, nil
+}
+
+// GetBalances retrieves the latest balance of several addresses in batch
+// requests
+func (c *Client) GetBalances(ctx context.Context, addresses []types.Address) (map[types.Address]*big.Int, error) {
+	raw := make([]string, len(addresses))
+	calls := make([]BatchElem, len(addresses))
+	for i, address := range addresses {
+		calls[i] = BatchElem{
+			Method: "eth_getBalance",
+			Params: []interface{}{address, "latest"},
+			Result: &raw[i],
+		}
+	}
+
+	if err := c.BatchCall(ctx, calls); err != nil {
+		return nil, fmt.Errorf("failed to fetch balances: %w", err)
+	}
+
+	balances := make(map[types.Address]*big.Int, len(addresses))
+	for i, address := range addresses {
+		if calls[i].Error != nil {
+			return nil, fmt.Errorf("failed to fetch balance of %s: %w", address, calls[i].Error)
+		}
+		balance, err := parseHexBig(raw[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert balance of %s: %v", address, err)
+		}
+		balances[address] = balance
+	}
+	return balances, nil
+}
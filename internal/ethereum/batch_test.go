@@ -0,0 +1,90 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBatchCallSplitsOversizedBatches(t *testing.T) {
+	const limit = 3
+	var batches []int
+
+	// Behaves like geth with a batch request limit
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqs []JSONRPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			t.Errorf("Expected a batch request: %v", err)
+			return
+		}
+		batches = append(batches, len(reqs))
+
+		if len(reqs) > limit {
+			json.NewEncoder(w).Encode([]map[string]interface{}{{
+				"jsonrpc": "2.0", "id": reqs[0].ID,
+				"error": map[string]interface{}{"code": -32600, "message": "batch too large"},
+			}})
+			return
+		}
+
+		// Answer in reverse order to exercise matching by ID
+		var resps []map[string]interface{}
+		for i := len(reqs) - 1; i >= 0; i-- {
+			req := reqs[i]
+			if req.Params[0] == "0x0000000000000000000000000000000000000bad" {
+				resps = append(resps, map[string]interface{}{
+					"jsonrpc": "2.0", "id": req.ID,
+					"error": map[string]interface{}{"code": -32000, "message": "unknown account"},
+				})
+				continue
+			}
+			resps = append(resps, map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": "0x2a"})
+		}
+		json.NewEncoder(w).Encode(resps)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	calls := make([]BatchElem, 5)
+	results := make([]string, len(calls))
+	for i := range calls {
+		address := "0x0000000000000000000000000000000000000001"
+		if i == 3 {
+			address = "0x0000000000000000000000000000000000000bad"
+		}
+		calls[i] = BatchElem{Method: "eth_getBalance", Params: []interface{}{address, "latest"}, Result: &results[i]}
+	}
+
+	if err := client.BatchCall(context.Background(), calls); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i, call := range calls {
+		if i == 3 {
+			if call.Error == nil {
+				t.Errorf("Expected an error for call %d", i)
+			}
+			continue
+		}
+		if call.Error != nil || results[i] != "0x2a" {
+			t.Errorf("Unexpected result for call %d: %q, %v", i, results[i], call.Error)
+		}
+	}
+
+	// The rejected batch of 5 is retried as batches of 2
+	want := []int{5, 2, 2, 1}
+	if len(batches) != len(want) {
+		t.Fatalf("Expected batches %v, got %v", want, batches)
+	}
+	for i := range want {
+		if batches[i] != want[i] {
+			t.Fatalf("Expected batches %v, got %v", want, batches)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package ethereum
+
+import (
+	"fmt"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// rpcBlockHeader is the JSON-RPC representation of the header fields of a
+// block
+type rpcBlockHeader struct {
+	Number        string `json:"number"`
+	Hash          string `json:"hash"`
+	ParentHash    string `json:"parentHash"`
+	Timestamp     string `json:"timestamp"`
+	BaseFeePerGas string `json:"baseFeePerGas"`
+	Miner         string `json:"miner"`
+	GasUsed       string `json:"gasUsed"`
+	GasLimit      string `json:"gasLimit"`
+
+	WithdrawalsRoot string `json:"withdrawalsRoot"`
+	BlobGasUsed     string `json:"blobGasUsed"`
+	ExcessBlobGas   string `json:"excessBlobGas"`
+}
+
+// rpcBlock is a block returned with full transaction objects
+type rpcBlock struct {
+	rpcBlockHeader
+	Transactions []rpcTransaction `json:"transactions"`
+}
+
+func (r rpcBlockHeader) toHeader() (types.BlockHeader, error) {
+	header := types.BlockHeader{Hash: r.Hash, ParentHash: r.ParentHash, WithdrawalsRoot: r.WithdrawalsRoot}
+
+	number, err := parseHexUint64(r.Number)
+	if err != nil {
+		return header, fmt.Errorf("number: %v", err)
+	}
+	header.Number = int64(number)
+
+	timestamp, err := parseHexUint64(r.Timestamp)
+	if err != nil {
+		return header, fmt.Errorf("timestamp: %v", err)
+	}
+	header.Timestamp = int64(timestamp)
+
+	if header.GasUsed, err = parseHexUint64(r.GasUsed); err != nil {
+		return header, fmt.Errorf("gasUsed: %v", err)
+	}
+	if header.GasLimit, err = parseHexUint64(r.GasLimit); err != nil {
+		return header, fmt.Errorf("gasLimit: %v", err)
+	}
+	if header.Miner, err = types.ParseAddress(r.Miner); err != nil {
+		return header, fmt.Errorf("miner: %v", err)
+	}
+
+	// Blocks before the London fork have no base fee
+	if r.BaseFeePerGas != "" {
+		if header.BaseFeePerGas, err = parseHexBig(r.BaseFeePerGas); err != nil {
+			return header, fmt.Errorf("baseFeePerGas: %v", err)
+		}
+	}
+
+	// Blocks before the Cancun fork have no blob gas accounting
+	if r.BlobGasUsed != "" {
+		used, err := parseHexUint64(r.BlobGasUsed)
+		if err != nil {
+			return header, fmt.Errorf("blobGasUsed: %v", err)
+		}
+		header.BlobGasUsed = &used
+	}
+	if r.ExcessBlobGas != "" {
+		excess, err := parseHexUint64(r.ExcessBlobGas)
+		if err != nil {
+			return header, fmt.Errorf("excessBlobGas: %v", err)
+		}
+		header.ExcessBlobGas = &excess
+	}
+
+	return header, nil
+}
+
+// toBlock converts the block fetched as blockNumber
+func (r rpcBlock) toBlock(blockNumber int64) (*types.Block, error) {
+	if r.Hash == "" {
+		return nil, fmt.Errorf("%w: %d", ErrBlockNotFound, blockNumber)
+	}
+
+	header, err := r.toHeader()
+	if err != nil {
+		return nil, fmt.Errorf("block %d: %v", blockNumber, err)
+	}
+
+	result := &types.Block{
+		BlockHeader:  header,
+		Transactions: make([]types.Transaction, 0, len(r.Transactions)),
+	}
+
+	for _, raw := range r.Transactions {
+		tx, err := raw.toTransaction()
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: %v", raw.Hash, err)
+		}
+		if tx.BlockNumber != header.Number {
+			return nil, fmt.Errorf("transaction %s: block number %d does not match block %d",
+				tx.Hash, tx.BlockNumber, header.Number)
+		}
+
+		// Transactions carry no time of their own
+		tx.BlockHash = header.Hash
+		tx.Timestamp = header.Timestamp
+		result.Transactions = append(result.Transactions, tx)
+	}
+
+	return result, nil
+}
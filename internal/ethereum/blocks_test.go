@@ -0,0 +1,143 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// headerFixtures are eth_getBlockByNumber results as a node returns them
+// for blocks on either side of the forks that changed the header
+var headerFixtures = map[string]string{
+	// Before London: no base fee
+	"0xc5043f": `{
+		"number": "0xc5043f",
+		"hash": "0x05bc2b1e4e9d8a54cba2b6a4c1f0d7e8bdf3b16c0f5b8c3f32a2e8a79c2f4c11",
+		"parentHash": "0x9b83c12c69edb74f6c8dd5d052765c1adf940e320bd1291696e6fa07829eee71",
+		"timestamp": "0x60e9d6a1",
+		"miner": "0xea674fdde714fd979de3edf0f56aa9716b898ec8",
+		"gasUsed": "0xe4e1b2",
+		"gasLimit": "0xe4e1c0",
+		"difficulty": "0x1bc2e1a6b6a5d1",
+		"transactions": []
+	}`,
+	// Shanghai: withdrawals but no blob gas
+	"0x1096e02": `{
+		"number": "0x1096e02",
+		"hash": "0x2f7b9f0c3b5a8c1c8c0f2a3dfd3e7f1a4a9e2d0d7b5d3c1e6f8a0b2c4d6e8f01",
+		"parentHash": "0x6a1e0f9b2d3c4b5a69788796a5b4c3d2e1f0a9b8c7d6e5f4a3b2c1d0e9f8a7b6",
+		"timestamp": "0x64373057",
+		"baseFeePerGas": "0x6d6e2edc9",
+		"miner": "0x388c818ca8b9251b393131c08a736a67ccb19297",
+		"gasUsed": "0xfc1e43",
+		"gasLimit": "0x1c9c380",
+		"withdrawalsRoot": "0x5de1e3e7d7b25b7c1f9a2ee05e0d3d6ad96e36d2c3b5e6b0a8a4c7e3d2f1b0a9",
+		"withdrawals": [{"index": "0x0", "validatorIndex": "0x4a", "address": "0x388c818ca8b9251b393131c08a736a67ccb19297", "amount": "0xdc4"}],
+		"transactions": []
+	}`,
+	// Cancun: withdrawals and blob gas
+	"0x1286d99": `{
+		"number": "0x1286d99",
+		"hash": "0xb3c2e5a1f3d4c6b8a9e0f1d2c3b4a5968778695a4b3c2d1e0f9a8b7c6d5e4f3a",
+		"parentHash": "0xc0ffee0000000000000000000000000000000000000000000000000000000001",
+		"timestamp": "0x65f1b057",
+		"baseFeePerGas": "0x4a817c800",
+		"miner": "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5",
+		"gasUsed": "0x1c9c380",
+		"gasLimit": "0x1c9c380",
+		"withdrawalsRoot": "0x7f4b1c9d2e3a5b6c8d0e1f2a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e",
+		"withdrawals": [],
+		"blobGasUsed": "0xc0000",
+		"excessBlobGas": "0x4b80000",
+		"parentBeaconBlockRoot": "0x0d2cbd1e0e1f2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f70",
+		"transactions": []
+	}`,
+	"0x1": `{
+		"number": "0x1",
+		"hash": "0x88e96d4537bea4d9c05d12549907b32561d3bf31f45aae734cdc119f13406cb6",
+		"parentHash": "0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3",
+		"timestamp": "0x55ba4224",
+		"miner": "0x05a56e2d52c817161883f50c441c3228cfe54d9f",
+		"gasUsed": "0x0",
+		"gasLimit": "0x1388",
+		"blobGasUsed": "blob",
+		"transactions": []
+	}`,
+}
+
+func newHeaderNode(t *testing.T) *Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req JSONRPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		result := json.RawMessage("null")
+		if fixture, ok := headerFixtures[req.Params[0].(string)]; ok {
+			result = json.RawMessage(fixture)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return client
+}
+
+func TestGetBlockHeaderDecodesForkFields(t *testing.T) {
+	client := newHeaderNode(t)
+
+	london, err := client.GetBlockHeader(context.Background(), 0xc5043f)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if london.Number != 0xc5043f || london.Timestamp != 0x60e9d6a1 ||
+		london.Miner != "0xea674fdde714fd979de3edf0f56aa9716b898ec8" ||
+		london.GasUsed != 0xe4e1b2 || london.GasLimit != 0xe4e1c0 ||
+		london.ParentHash != "0x9b83c12c69edb74f6c8dd5d052765c1adf940e320bd1291696e6fa07829eee71" {
+		t.Errorf("Unexpected header: %+v", london)
+	}
+	if london.BaseFeePerGas != nil || london.WithdrawalsRoot != "" || london.BlobGasUsed != nil || london.ExcessBlobGas != nil {
+		t.Errorf("Expected no post-London fields before the fork, got %+v", london)
+	}
+
+	shanghai, err := client.GetBlockHeader(context.Background(), 0x1096e02)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if shanghai.BaseFeePerGas == nil || shanghai.BaseFeePerGas.Uint64() != 0x6d6e2edc9 {
+		t.Errorf("Unexpected base fee: %v", shanghai.BaseFeePerGas)
+	}
+	if shanghai.WithdrawalsRoot != "0x5de1e3e7d7b25b7c1f9a2ee05e0d3d6ad96e36d2c3b5e6b0a8a4c7e3d2f1b0a9" {
+		t.Errorf("Unexpected withdrawals root: %s", shanghai.WithdrawalsRoot)
+	}
+	if shanghai.BlobGasUsed != nil || shanghai.ExcessBlobGas != nil {
+		t.Errorf("Expected no blob gas before Cancun, got %+v", shanghai)
+	}
+
+	cancun, err := client.GetBlockHeader(context.Background(), 0x1286d99)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cancun.WithdrawalsRoot == "" || cancun.BlobGasUsed == nil || cancun.ExcessBlobGas == nil {
+		t.Fatalf("Expected Cancun fields, got %+v", cancun)
+	}
+	if *cancun.BlobGasUsed != 0xc0000 || *cancun.ExcessBlobGas != 0x4b80000 {
+		t.Errorf("Unexpected blob gas: used %d, excess %d", *cancun.BlobGasUsed, *cancun.ExcessBlobGas)
+	}
+}
+
+func TestGetBlockHeaderErrors(t *testing.T) {
+	client := newHeaderNode(t)
+
+	if _, err := client.GetBlockHeader(context.Background(), 1); err == nil {
+		t.Errorf("Expected an error for a malformed blobGasUsed")
+	}
+	if _, err := client.GetBlockHeader(context.Background(), 2); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("Expected ErrBlockNotFound for a missing block, got %v", err)
+	}
+}
@@ -1,15 +1,11 @@
 package ethereum
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"math/big"
-	"net/http"
-	"strconv"
-	"time"
+	"sync/atomic"
 
 	"github.com/ethereum_parser/internal/types"
 )
@@ -30,140 +26,163 @@ type JSONRPCResponse struct {
 	ID      int             `json:"id"`
 }
 
-// RPCError represents JSON-RPC error structure
-type RPCError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-}
-
 // Client handles Ethereum JSON-RPC interactions
 type Client struct {
-	rpcURL     string
-	httpClient *http.Client
+	transport transport
+	// lastID numbers the requests so that responses arriving over a
+	// shared connection can be matched to them
+	lastID atomic.Int64
+	// batchSize is the largest batch sent to the node, lowered when the
+	// node rejects a batch as too large
+	batchSize atomic.Int64
+
+	// blockReceiptsUnsupported is set once the node rejects eth_getBlockReceipts
+	blockReceiptsUnsupported atomic.Bool
 }
 
-// NewClient creates a new Ethereum JSON-RPC client
+// NewClient creates a new Ethereum JSON-RPC client. rpcURL selects the
+// transport: http(s) URLs use one HTTP request per call; ws(s) URLs, and
+// ipc:// URLs or absolute socket paths of a co-located node, use a persistent
+// connection that also supports subscriptions.
 func NewClient(rpcURL string) (*Client, error) {
 	if rpcURL == "" {
 		return nil, fmt.Errorf("RPC URL cannot be empty")
 	}
 
-	return &Client{
-		rpcURL: rpcURL,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-	}, nil
+	t, err := newTransport(rpcURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return newClient(t), nil
+}
+
+func newClient(t transport) *Client {
+	client := &Client{transport: t}
+	client.batchSize.Store(defaultBatchSize)
+	return client
 }
 
-// makeJSONRPCRequest sends a JSON-RPC request and returns the response
-func (c *Client) makeJSONRPCRequest(method string, params []interface{}) (*JSONRPCResponse, error) {
-	// Prepare request payload
-	payload := JSONRPCRequest{
+// newRequest builds a JSON-RPC request with a fresh ID
+func (c *Client) newRequest(method string, params []interface{}) *JSONRPCRequest {
+	// Some nodes reject null params
+	if params == nil {
+		params = []interface{}{}
+	}
+	return &JSONRPCRequest{
 		JSONRPC: "2.0",
 		Method:  method,
 		Params:  params,
-		ID:      1,
+		ID:      int(c.lastID.Add(1)),
 	}
+}
 
-	// Convert payload to JSON
-	jsonPayload, err := json.Marshal(payload)
+// makeJSONRPCRequest sends a JSON-RPC request and returns the response,
+// retrying transient failures until ctx expires
+func (c *Client) makeJSONRPCRequest(ctx context.Context, method string, params []interface{}) (*JSONRPCResponse, error) {
+	var rpcResp *JSONRPCResponse
+	err := c.retry(ctx, method, func() error {
+		resp, err := c.transport.roundTrip(ctx, c.newRequest(method, params))
+		if err != nil {
+			return err
+		}
+		// Check for RPC error
+		if resp.Error != nil {
+			return rpcError(method, resp.Error)
+		}
+		rpcResp = resp
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal JSON-RPC request: %v", err)
+		return nil, err
 	}
+	return rpcResp, nil
+}
 
-	// Create HTTP request
-	req, err := http.NewRequest("POST", c.rpcURL, bytes.NewBuffer(jsonPayload))
+// GetBlockNumber retrieves the latest block number
+func (c *Client) GetBlockNumber(ctx context.Context) (int64, error) {
+	resp, err := c.makeJSONRPCRequest(ctx, "eth_blockNumber", []interface{}{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %v", err)
+		return 0, err
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	// Send request
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send HTTP request: %v", err)
+	var hexBlockNum string
+	if err := json.Unmarshal(resp.Result, &hexBlockNum); err != nil {
+		return 0, fmt.Errorf("failed to parse block number: %v", err)
 	}
-	defer resp.Body.Close()
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	blockNum, err := parseHexUint64(hexBlockNum)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+		return 0, fmt.Errorf("failed to convert block number: %v", err)
 	}
 
-	// Parse JSON-RPC response
-	var rpcResp JSONRPCResponse
-	if err := json.Unmarshal(body, &rpcResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal JSON-RPC response: %v", err)
-	}
+	return int64(blockNum), nil
+}
 
-	// Check for RPC error
-	if rpcResp.Error != nil {
-		return nil, fmt.Errorf("RPC error: code %d, message: %s",
-			rpcResp.Error.Code, rpcResp.Error.Message)
-	}
+// GetBlockHeader retrieves the header of the block with the given number
+func (c *Client) GetBlockHeader(ctx context.Context, blockNumber int64) (*types.BlockHeader, error) {
+	return c.getBlockHeader(ctx, fmt.Sprintf("0x%x", blockNumber))
+}
 
-	return &rpcResp, nil
+// GetBlockHeaderByTag retrieves the header of a tagged block such as "safe"
+// or "finalized"
+func (c *Client) GetBlockHeaderByTag(ctx context.Context, tag string) (*types.BlockHeader, error) {
+	return c.getBlockHeader(ctx, tag)
 }
 
-// GetBlockNumber retrieves the latest block number
-func (c *Client) GetBlockNumber() (int64, error) {
-	resp, err := c.makeJSONRPCRequest("eth_blockNumber", []interface{}{})
+func (c *Client) getBlockHeader(ctx context.Context, block string) (*types.BlockHeader, error) {
+	resp, err := c.makeJSONRPCRequest(ctx, "eth_getBlockByNumber",
+		[]interface{}{block, false})
 	if err != nil {
-		return 0, err
+		return nil, fmt.Errorf("failed to fetch block header: %w", err)
 	}
 
-	// Remove "0x" prefix and convert hex to int64
-	var hexBlockNum string
-	if err := json.Unmarshal(resp.Result, &hexBlockNum); err != nil {
-		return 0, fmt.Errorf("failed to parse block number: %v", err)
+	var raw rpcBlockHeader
+	if err := json.Unmarshal(resp.Result, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse block header: %v", err)
+	}
+	if raw.Hash == "" {
+		return nil, fmt.Errorf("%w: %s", ErrBlockNotFound, block)
 	}
 
-	blockNum, err := strconv.ParseInt(hexBlockNum[2:], 16, 64)
+	header, err := raw.toHeader()
 	if err != nil {
-		return 0, fmt.Errorf("failed to convert block number: %v", err)
+		return nil, fmt.Errorf("block %s: %v", block, err)
 	}
-
-	return blockNum, nil
+	return &header, nil
 }
 
-// GetTransactionsForAddress retrieves transactions for a specific address
-func (c *Client) GetTransactionsForAddress(
-	ctx context.Context,
-	address string,
-	blockNumber int64,
-) ([]types.Transaction, error) {
-	// Validate address
-	if !isValidEthereumAddress(address) {
-		return nil, fmt.Errorf("invalid Ethereum address: %s", address)
-	}
-
+// GetBlock retrieves a block together with all of its transactions
+func (c *Client) GetBlock(ctx context.Context, blockNumber int64) (*types.Block, error) {
 	// Convert block number to hex
 	blockNumberHex := fmt.Sprintf("0x%x", blockNumber)
 
 	// Fetch block details
-	blockResp, err := c.makeJSONRPCRequest("eth_getBlockByNumber",
+	blockResp, err := c.makeJSONRPCRequest(ctx, "eth_getBlockByNumber",
 		[]interface{}{blockNumberHex, true})
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch block: %v", err)
-	}
-
-	// Parse block transactions
-	var block struct {
-		Transactions []struct {
-			Hash        string `json:"hash"`
-			From        string `json:"from"`
-			To          string `json:"to"`
-			Value       string `json:"value"`
-			BlockNumber string `json:"blockNumber"`
-			Timestamp   string `json:"timestamp"`
-		} `json:"transactions"`
+		return nil, fmt.Errorf("failed to fetch block: %w", err)
 	}
 
+	var block rpcBlock
 	if err := json.Unmarshal(blockResp.Result, &block); err != nil {
 		return nil, fmt.Errorf("failed to parse block transactions: %v", err)
 	}
+	return block.toBlock(blockNumber)
+}
+
+// GetTransactionsForAddress retrieves transactions for a specific address.
+// Callers interested in several addresses should fetch the block once with
+// GetBlock instead.
+func (c *Client) GetTransactionsForAddress(
+	ctx context.Context,
+	address types.Address,
+	blockNumber int64,
+) ([]types.Transaction, error) {
+	block, err := c.GetBlock(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
 
 	var transactions []types.Transaction
 
@@ -172,34 +191,16 @@ func (c *Client) GetTransactionsForAddress(
 		if tx.From != address && (tx.To == "" || tx.To != address) {
 			continue
 		}
-
-		// Convert hex values
-		value, _ := new(big.Int).SetString(tx.Value[2:], 16)
-		blockNum, _ := strconv.ParseInt(tx.BlockNumber[2:], 16, 64)
-		timestamp, _ := strconv.ParseInt(tx.Timestamp[2:], 16, 64)
-
-		transactions = append(transactions, types.Transaction{
-			Hash:        tx.Hash,
-			From:        tx.From,
-			To:          tx.To,
-			Value:       value,
-			BlockNumber: blockNum,
-			Timestamp:   timestamp,
-		})
+		transactions = append(transactions, tx)
 	}
 
 	return transactions, nil
 }
 
 // GetBalance retrieves the balance of an address
-func (c *Client) GetBalance(address string) (*big.Int, error) {
-	// Validate address
-	if !isValidEthereumAddress(address) {
-		return nil, fmt.Errorf("invalid Ethereum address: %s", address)
-	}
-
+func (c *Client) GetBalance(ctx context.Context, address types.Address) (*big.Int, error) {
 	// Get balance
-	resp, err := c.makeJSONRPCRequest("eth_getBalance",
+	resp, err := c.makeJSONRPCRequest(ctx, "eth_getBalance",
 		[]interface{}{address, "latest"})
 	if err != nil {
 		return nil, err
@@ -212,22 +213,75 @@ func (c *Client) GetBalance(address string) (*big.Int, error) {
 	}
 
 	// Convert hex to big.Int (wei)
-	balance, ok := new(big.Int).SetString(hexBalance[2:], 16)
-	if !ok {
-		return nil, fmt.Errorf("failed to convert balance")
+	balance, err := parseHexBig(hexBalance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert balance: %v", err)
 	}
 
 	return balance, nil
 }
 
-// Helper function to validate Ethereum address
-func isValidEthereumAddress(address string) bool {
-	// Basic validation
-	if len(address) != 42 {
-		return false
+// GetLogs retrieves the event logs matching filter
+func (c *Client) GetLogs(ctx context.Context, filter types.LogFilter) ([]types.Log, error) {
+	params := map[string]interface{}{}
+	if filter.BlockHash != "" {
+		params["blockHash"] = filter.BlockHash
+	} else {
+		params["fromBlock"] = fmt.Sprintf("0x%x", filter.FromBlock)
+		params["toBlock"] = fmt.Sprintf("0x%x", filter.ToBlock)
+	}
+	if len(filter.Addresses) > 0 {
+		params["address"] = filter.Addresses
+	}
+	if len(filter.Topics) > 0 {
+		params["topics"] = filter.Topics
+	}
+
+	resp, err := c.makeJSONRPCRequest(ctx, "eth_getLogs", []interface{}{params})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch logs: %w", err)
+	}
+
+	var raw []struct {
+		Address         string   `json:"address"`
+		Topics          []string `json:"topics"`
+		Data            string   `json:"data"`
+		BlockNumber     string   `json:"blockNumber"`
+		BlockHash       string   `json:"blockHash"`
+		TransactionHash string   `json:"transactionHash"`
+		LogIndex        string   `json:"logIndex"`
+		Removed         bool     `json:"removed"`
 	}
-	if address[:2] != "0x" {
-		return false
+	if err := json.Unmarshal(resp.Result, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse logs: %v", err)
 	}
-	return true
+
+	logs := make([]types.Log, 0, len(raw))
+	for _, l := range raw {
+		address, err := types.ParseAddress(l.Address)
+		if err != nil {
+			return nil, fmt.Errorf("log in transaction %s: %v", l.TransactionHash, err)
+		}
+		blockNumber, err := parseHexUint64(l.BlockNumber)
+		if err != nil {
+			return nil, fmt.Errorf("log in transaction %s: blockNumber: %v", l.TransactionHash, err)
+		}
+		logIndex, err := parseHexUint64(l.LogIndex)
+		if err != nil {
+			return nil, fmt.Errorf("log in transaction %s: logIndex: %v", l.TransactionHash, err)
+		}
+
+		logs = append(logs, types.Log{
+			Address:         address,
+			Topics:          l.Topics,
+			Data:            l.Data,
+			BlockNumber:     int64(blockNumber),
+			BlockHash:       l.BlockHash,
+			TransactionHash: l.TransactionHash,
+			LogIndex:        logIndex,
+			Removed:         l.Removed,
+		})
+	}
+
+	return logs, nil
 }
@@ -0,0 +1,125 @@
+package ethereum
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Well-known JSON-RPC error codes
+const (
+	codeInternalError     = -32603
+	codeInvalidParams     = -32602
+	codeMethodNotFound    = -32601
+	codeLimitExceeded     = -32005
+	codeExecutionReverted = 3
+)
+
+// Errors reported by the node, matched with errors.Is against the *RPCError
+// or *HTTPError returned by the client
+var (
+	// ErrMethodNotFound is returned when the node does not implement a method
+	ErrMethodNotFound = errors.New("method not found")
+	// ErrInvalidParams is returned when the node rejects the parameters of a call
+	ErrInvalidParams = errors.New("invalid params")
+	// ErrRateLimited is returned when the node refuses a call because of a
+	// request limit
+	ErrRateLimited = errors.New("rate limited")
+	// ErrExecutionReverted is returned when a call reverts; the revert data
+	// is available through RPCError.RevertData
+	ErrExecutionReverted = errors.New("execution reverted")
+	// ErrBlockNotFound is returned when the node does not know a block yet
+	ErrBlockNotFound = errors.New("block not found")
+)
+
+// RPCError is an error response of the node
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	// Method is the JSON-RPC method that failed
+	Method string `json:"-"`
+}
+
+func (e *RPCError) Error() string {
+	if e.Method == "" {
+		return fmt.Sprintf("RPC error: code %d, message: %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("%s: RPC error: code %d, message: %s", e.Method, e.Code, e.Message)
+}
+
+// Is matches the error against the well-known errors. Providers that do not
+// use the standard codes say so in the message.
+func (e *RPCError) Is(target error) bool {
+	message := strings.ToLower(e.Message)
+
+	switch target {
+	case ErrMethodNotFound:
+		return e.Code == codeMethodNotFound
+	case ErrInvalidParams:
+		return e.Code == codeInvalidParams
+	case ErrRateLimited:
+		return e.Code == codeLimitExceeded || e.Code == http.StatusTooManyRequests ||
+			strings.Contains(message, "rate limit") ||
+			strings.Contains(message, "too many requests") ||
+			strings.Contains(message, "limit exceeded")
+	case ErrExecutionReverted:
+		return e.Code == codeExecutionReverted || strings.HasPrefix(message, "execution reverted")
+	case ErrBlockNotFound:
+		return strings.Contains(message, "header not found") || strings.Contains(message, "block not found")
+	}
+	return false
+}
+
+// RevertData returns the data a reverted call returned, or nil if the node
+// did not include any
+func (e *RPCError) RevertData() []byte {
+	var hexData string
+	if json.Unmarshal(e.Data, &hexData) != nil {
+		return nil
+	}
+	data, err := hexutil.Decode(hexData)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// RevertReason decodes the Error(string) or Panic(uint256) message of a
+// reverted call, if there is one
+func (e *RPCError) RevertReason() (string, bool) {
+	reason, err := abi.UnpackRevert(e.RevertData())
+	if err != nil {
+		return "", false
+	}
+	return reason, true
+}
+
+// rpcError attributes an error returned by the node to method
+func rpcError(method string, rpcErr *RPCError) error {
+	rpcErr.Method = method
+	return rpcErr
+}
+
+// HTTPError is returned for HTTP error responses without a JSON-RPC error
+// in their body
+type HTTPError struct {
+	StatusCode int
+	// RetryAfter is the delay requested by the Retry-After header
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Is matches 429 responses against ErrRateLimited
+func (e *HTTPError) Is(target error) bool {
+	return target == ErrRateLimited && e.StatusCode == http.StatusTooManyRequests
+}
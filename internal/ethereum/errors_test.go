@@ -0,0 +1,57 @@
+package ethereum
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestRPCErrorMatchesWellKnownErrors(t *testing.T) {
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{&RPCError{Code: codeMethodNotFound, Message: "the method eth_foo does not exist"}, ErrMethodNotFound},
+		{&RPCError{Code: codeInvalidParams, Message: "invalid argument 0"}, ErrInvalidParams},
+		{&RPCError{Code: codeLimitExceeded, Message: "request limit reached"}, ErrRateLimited},
+		{&RPCError{Code: -32000, Message: "Too Many Requests"}, ErrRateLimited},
+		{&RPCError{Code: -32000, Message: "header not found"}, ErrBlockNotFound},
+		{&RPCError{Code: codeExecutionReverted, Message: "execution reverted: nope"}, ErrExecutionReverted},
+		{&HTTPError{StatusCode: http.StatusTooManyRequests}, ErrRateLimited},
+	}
+
+	for _, tt := range tests {
+		// Errors keep matching when wrapped by the client
+		err := fmt.Errorf("failed to fetch block: %w", tt.err)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Expected %q to match %q", tt.err, tt.want)
+		}
+		if errors.Is(err, ErrMethodNotFound) != (tt.want == ErrMethodNotFound) {
+			t.Errorf("Unexpected match of %q with %q", tt.err, ErrMethodNotFound)
+		}
+	}
+}
+
+func TestRPCErrorRevertReason(t *testing.T) {
+	var resp JSONRPCResponse
+	body := `{"jsonrpc":"2.0","id":1,"error":{"code":3,"message":"execution reverted: nope","data":"0x08c379a0` +
+		`0000000000000000000000000000000000000000000000000000000000000020` +
+		`0000000000000000000000000000000000000000000000000000000000000004` +
+		`6e6f706500000000000000000000000000000000000000000000000000000000"}}`
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var rpcErr *RPCError
+	if !errors.As(rpcError("eth_call", resp.Error), &rpcErr) {
+		t.Fatalf("Expected an RPCError")
+	}
+	if len(rpcErr.RevertData()) != 100 {
+		t.Errorf("Expected 100 bytes of revert data, got %d", len(rpcErr.RevertData()))
+	}
+	if reason, ok := rpcErr.RevertReason(); !ok || reason != "nope" {
+		t.Errorf("Expected revert reason nope, got %q", reason)
+	}
+}
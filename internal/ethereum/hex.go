@@ -0,0 +1,32 @@
+package ethereum
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// parseHexUint64 decodes a 0x-prefixed JSON-RPC quantity
+func parseHexUint64(s string) (uint64, error) {
+	if !strings.HasPrefix(s, "0x") || len(s) < 3 {
+		return 0, fmt.Errorf("invalid hex quantity %q", s)
+	}
+	v, err := strconv.ParseUint(s[2:], 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hex quantity %q: %v", s, err)
+	}
+	return v, nil
+}
+
+// parseHexBig decodes a 0x-prefixed JSON-RPC quantity of arbitrary size
+func parseHexBig(s string) (*big.Int, error) {
+	if !strings.HasPrefix(s, "0x") || len(s) < 3 {
+		return nil, fmt.Errorf("invalid hex quantity %q", s)
+	}
+	v, ok := new(big.Int).SetString(s[2:], 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid hex quantity %q", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,50 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ipcConn is a connection to the IPC endpoint of a co-located node over a
+// Unix domain socket. Messages are consecutive JSON values on the stream.
+type ipcConn struct {
+	conn    net.Conn
+	decoder *json.Decoder
+}
+
+// dialIPC returns a dialFunc connecting to the Unix socket at path
+func dialIPC(path string) dialFunc {
+	return func(ctx context.Context) (streamConn, error) {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "unix", path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %v", path, err)
+		}
+		return &ipcConn{conn: conn, decoder: json.NewDecoder(conn)}, nil
+	}
+}
+
+func (c *ipcConn) readMessage() ([]byte, error) {
+	var msg json.RawMessage
+	if err := c.decoder.Decode(&msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (c *ipcConn) writeMessage(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(requestTimeout))
+	_, err = c.conn.Write(data)
+	return err
+}
+
+func (c *ipcConn) close() error {
+	return c.conn.Close()
+}
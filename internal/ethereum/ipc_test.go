@@ -0,0 +1,107 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+func TestIPCTransport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.ipc")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		decoder := json.NewDecoder(conn)
+		encoder := json.NewEncoder(conn)
+		for {
+			var msg json.RawMessage
+			if err := decoder.Decode(&msg); err != nil {
+				return
+			}
+
+			var reqs []JSONRPCRequest
+			if isBatchResponse(msg) {
+				json.Unmarshal(msg, &reqs)
+			} else {
+				var req JSONRPCRequest
+				json.Unmarshal(msg, &req)
+				reqs = append(reqs, req)
+			}
+
+			var resps []interface{}
+			for _, req := range reqs {
+				if req.Method == "eth_blockNumber" {
+					resps = append(resps, map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": "0x64"})
+					continue
+				}
+				resps = append(resps, map[string]interface{}{
+					"jsonrpc": "2.0", "id": req.ID,
+					"error": map[string]interface{}{"code": -32601, "message": "method not found"},
+				})
+			}
+
+			if isBatchResponse(msg) {
+				encoder.Encode(resps)
+			} else {
+				encoder.Encode(resps[0])
+			}
+		}
+	}()
+
+	for _, rpcURL := range []string{path, "ipc://" + path} {
+		client, err := NewClient(rpcURL)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, ok := client.transport.(*streamTransport); !ok {
+			t.Fatalf("Expected a stream transport for %s, got %T", rpcURL, client.transport)
+		}
+	}
+
+	client, _ := NewClient(path)
+	number, err := client.GetBlockNumber(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if number != 100 {
+		t.Errorf("Expected block 100, got %d", number)
+	}
+
+	calls := []BatchElem{{Method: "eth_blockNumber"}, {Method: "eth_syncing"}}
+	if err := client.BatchCall(context.Background(), calls); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls[0].Error != nil || !errors.Is(calls[1].Error, ErrMethodNotFound) {
+		t.Errorf("Unexpected batch errors: %v, %v", calls[0].Error, calls[1].Error)
+	}
+
+	// Errors are mapped the same way as over HTTP
+	_, err = client.SubscribeNewHeads(context.Background(), make(chan types.BlockHeader))
+	if !errors.Is(err, ErrSubscriptionsUnsupported) {
+		t.Errorf("Expected ErrSubscriptionsUnsupported, got %v", err)
+	}
+}
+
+func TestNewClientRejectsRelativeURLs(t *testing.T) {
+	// Without a scheme only absolute paths are taken as IPC sockets
+	for _, rpcURL := range []string{"localhost:8545", "127.0.0.1:8545", "geth.ipc", "ftp://node"} {
+		if _, err := NewClient(rpcURL); err == nil {
+			t.Errorf("Expected an error for %s", rpcURL)
+		}
+	}
+}
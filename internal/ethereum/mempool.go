@@ -0,0 +1,105 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// NewPendingTransactionFilter installs a filter on the node that collects
+// the hashes of transactions entering its mempool
+func (c *Client) NewPendingTransactionFilter(ctx context.Context) (string, error) {
+	resp, err := c.makeJSONRPCRequest(ctx, "eth_newPendingTransactionFilter", []interface{}{})
+	if err != nil {
+		return "", fmt.Errorf("failed to create pending transaction filter: %w", err)
+	}
+
+	var id string
+	if err := json.Unmarshal(resp.Result, &id); err != nil {
+		return "", fmt.Errorf("failed to parse filter ID: %v", err)
+	}
+	return id, nil
+}
+
+// GetFilterChanges returns the transaction hashes collected by a pending
+// transaction filter since the previous call
+func (c *Client) GetFilterChanges(ctx context.Context, filterID string) ([]string, error) {
+	resp, err := c.makeJSONRPCRequest(ctx, "eth_getFilterChanges", []interface{}{filterID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get filter changes: %w", err)
+	}
+
+	var hashes []string
+	if err := json.Unmarshal(resp.Result, &hashes); err != nil {
+		return nil, fmt.Errorf("failed to parse filter changes: %v", err)
+	}
+	return hashes, nil
+}
+
+// UninstallFilter removes a filter from the node
+func (c *Client) UninstallFilter(ctx context.Context, filterID string) error {
+	if _, err := c.makeJSONRPCRequest(ctx, "eth_uninstallFilter", []interface{}{filterID}); err != nil {
+		return fmt.Errorf("failed to uninstall filter: %w", err)
+	}
+	return nil
+}
+
+// GetTransactionByHash retrieves a pending or mined transaction. It returns
+// nil if the node does not know the transaction.
+func (c *Client) GetTransactionByHash(ctx context.Context, hash string) (*types.Transaction, error) {
+	resp, err := c.makeJSONRPCRequest(ctx, "eth_getTransactionByHash", []interface{}{hash})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+
+	var raw *rpcTransaction
+	if err := json.Unmarshal(resp.Result, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse transaction: %v", err)
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	tx, err := raw.toTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("transaction %s: %v", hash, err)
+	}
+	return &tx, nil
+}
+
+// GetTransactionsByHash retrieves several transactions in batch requests,
+// returned in the order of hashes. Transactions the node does not know are
+// nil.
+func (c *Client) GetTransactionsByHash(ctx context.Context, hashes []string) ([]*types.Transaction, error) {
+	raw := make([]*rpcTransaction, len(hashes))
+	calls := make([]BatchElem, len(hashes))
+	for i, hash := range hashes {
+		calls[i] = BatchElem{
+			Method: "eth_getTransactionByHash",
+			Params: []interface{}{hash},
+			Result: &raw[i],
+		}
+	}
+
+	if err := c.BatchCall(ctx, calls); err != nil {
+		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
+	}
+
+	txs := make([]*types.Transaction, len(hashes))
+	for i, hash := range hashes {
+		if calls[i].Error != nil {
+			return nil, fmt.Errorf("failed to fetch transaction %s: %w", hash, calls[i].Error)
+		}
+		if raw[i] == nil {
+			continue
+		}
+		tx, err := raw[i].toTransaction()
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: %v", hash, err)
+		}
+		txs[i] = &tx
+	}
+	return txs, nil
+}
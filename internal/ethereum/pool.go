@@ -0,0 +1,480 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// probeTimeout bounds the head probe of a single endpoint
+	probeTimeout = 3 * time.Second
+	// latencyDecay and errorDecay are the weights of the newest sample in
+	// the moving averages of an endpoint's latency and error rate
+	latencyDecay = 0.2
+	errorDecay   = 0.1
+)
+
+// Endpoint is an RPC endpoint of a provider pool
+type Endpoint struct {
+	URL string
+	// Weight scales how strongly the endpoint is preferred over equally
+	// healthy ones
+	Weight int
+}
+
+// PoolConfig tunes how a provider pool rates its endpoints
+type PoolConfig struct {
+	// MaxHeadLag is how many blocks an endpoint may trail the highest
+	// observed head before it is ejected
+	MaxHeadLag int64
+	// Probation is how long an ejected endpoint is kept out of rotation
+	// before it is probed again
+	Probation time.Duration
+	// Quorum is the number of endpoints that must agree on a block before
+	// it is indexed; see VerifyBlock. Values below 2 disable quorum mode.
+	Quorum int
+	// RateLimit is the average number of calls per second sent to each
+	// endpoint, in bursts of up to RateBurst calls. Zero disables the
+	// limit.
+	RateLimit float64
+	RateBurst int
+}
+
+// provider is an endpoint of the pool together with its health
+type provider struct {
+	// name identifies the endpoint in logs and disagreements without exposing
+	// credentials in its URL
+	name      string
+	weight    int
+	transport transport
+	// client talks to this endpoint alone, for quorum checks
+	client *Client
+
+	// latency and errorRate are moving averages over recent calls
+	latency   time.Duration
+	errorRate float64
+	// head is the last block number the endpoint reported
+	head int64
+	// ejectedUntil is set while the endpoint is out of rotation. Once it
+	// passes, the next head probe re-admits the endpoint if it has caught up
+	// and extends its probation otherwise.
+	ejectedUntil time.Time
+}
+
+// score rates the provider; healthier providers score higher
+func (p *provider) score() float64 {
+	latency := p.latency.Seconds() + 0.01
+	return float64(p.weight) * (1 - p.errorRate) / latency
+}
+
+// poolTransport routes every call to the healthiest of several endpoints,
+// failing over to the next one when an endpoint cannot be reached.
+// Endpoints are rated by latency and error rate, and by how far their head
+// trails the highest head of the pool. The heads are probed whenever the
+// block number is requested, so the pool keeps up with the chain as long
+// as the poller does.
+type poolTransport struct {
+	cfg       PoolConfig
+	providers []*provider
+
+	mu sync.Mutex
+	// head is the highest block number reported by any endpoint
+	head int64
+	// filters pins filters to the endpoint that installed them, as filter
+	// IDs are only known to that node
+	filters map[string]*provider
+}
+
+// NewPoolClient creates a client that spreads its calls over several
+// endpoints. A single endpoint is used directly, like NewClient does.
+func NewPoolClient(endpoints []Endpoint, cfg PoolConfig) (*Client, error) {
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no RPC endpoints configured")
+	}
+	if cfg.Quorum > len(endpoints) {
+		return nil, fmt.Errorf("a quorum of %d needs at least %d RPC endpoints, got %d", cfg.Quorum, cfg.Quorum, len(endpoints))
+	}
+	if len(endpoints) == 1 {
+		t, err := cfg.newTransport(endpoints[0].URL)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("RPC endpoint: %s", redactURL(endpoints[0].URL))
+		return newClient(t), nil
+	}
+
+	pool := &poolTransport{cfg: cfg, filters: make(map[string]*provider)}
+	for _, endpoint := range endpoints {
+		t, err := cfg.newTransport(endpoint.URL)
+		if err != nil {
+			return nil, err
+		}
+		p := &provider{
+			name:      endpointName(endpoint.URL, len(pool.providers)),
+			weight:    max(endpoint.Weight, 1),
+			transport: t,
+			client:    newClient(t),
+		}
+		pool.providers = append(pool.providers, p)
+		log.Printf("RPC endpoint %s, weight %d", p.name, p.weight)
+	}
+
+	return newClient(pool), nil
+}
+
+// newTransport connects to an endpoint of the pool, applying the rate limit
+func (cfg PoolConfig) newTransport(rpcURL string) (transport, error) {
+	if rpcURL == "" {
+		return nil, fmt.Errorf("RPC URL cannot be empty")
+	}
+	t, err := newTransport(rpcURL)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.RateLimit > 0 {
+		t = &limitedTransport{next: t, limiter: newTokenBucket(cfg.RateLimit, cfg.RateBurst)}
+	}
+	return t, nil
+}
+
+// endpointName identifies the i-th endpoint by its scheme and host, leaving
+// out the path and user info that often carry API keys
+func endpointName(rawURL string, i int) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		// Socket paths hold no secrets
+		return rawURL
+	}
+	return fmt.Sprintf("%d:%s", i+1, redactURL(rawURL))
+}
+
+// redactURL reduces rawURL to its scheme and host, dropping the path and
+// query that often hold an API key
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+}
+
+// ranked returns the providers in rotation, healthiest first. Providers on
+// probation are only returned when no other provider is left.
+func (t *poolTransport) ranked() []*provider {
+	active, ejected := t.ordered()
+	if len(active) == 0 {
+		return ejected
+	}
+	return active
+}
+
+// ordered returns the providers in rotation and those ejected, each
+// healthiest first
+func (t *poolTransport) ordered() (active, ejected []*provider) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, p := range t.providers {
+		if !p.ejectedUntil.IsZero() {
+			ejected = append(ejected, p)
+		} else {
+			active = append(active, p)
+		}
+	}
+
+	for _, list := range [][]*provider{active, ejected} {
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].score() > list[j].score()
+		})
+	}
+	return active, ejected
+}
+
+// record updates the health of p after a call that took latency
+func (t *poolTransport) record(p *provider, latency time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	failed := 0.0
+	if err != nil {
+		failed = 1
+	} else if p.latency == 0 {
+		p.latency = latency
+	} else {
+		p.latency += time.Duration(latencyDecay * float64(latency-p.latency))
+	}
+	p.errorRate += errorDecay * (failed - p.errorRate)
+}
+
+func (t *poolTransport) roundTrip(ctx context.Context, req *JSONRPCRequest) (*JSONRPCResponse, error) {
+	if req.Method == "eth_blockNumber" {
+		return t.probeHeads(ctx, req)
+	}
+	if p := t.filterProvider(req); p != nil {
+		// Filters only exist on this endpoint, so its answer is final
+		resp, err := t.send(ctx, p, req)
+		if resp != nil {
+			return resp, nil
+		}
+		return nil, err
+	}
+
+	var lastErr error
+	var refused *JSONRPCResponse
+	for _, p := range t.ranked() {
+		resp, err := t.send(ctx, p, req)
+		if err == nil {
+			t.trackFilter(p, req, resp)
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		log.Printf("RPC endpoint %s failed, trying the next one: %v", p.name, err)
+		lastErr = err
+		if resp != nil {
+			refused = resp
+		}
+	}
+	// When every endpoint refused the call, the caller gets the node's answer
+	if refused != nil {
+		return refused, nil
+	}
+	return nil, lastErr
+}
+
+func (t *poolTransport) batchRoundTrip(ctx context.Context, reqs []*JSONRPCRequest) ([]*JSONRPCResponse, error) {
+	var lastErr error
+	var refused []*JSONRPCResponse
+	for _, p := range t.ranked() {
+		start := time.Now()
+		resps, err := p.transport.batchRoundTrip(ctx, reqs)
+		// A batch the endpoint refuses because of its size says nothing
+		// about its health
+		if errors.Is(err, errBatchTooLarge) {
+			return nil, err
+		}
+		if err == nil {
+			err = batchFault(reqs, resps)
+		}
+		t.record(p, time.Since(start), err)
+		if err == nil {
+			return resps, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		log.Printf("RPC endpoint %s failed, trying the next one: %v", p.name, err)
+		lastErr = err
+		if resps != nil {
+			refused = resps
+		}
+	}
+	if refused != nil {
+		return refused, nil
+	}
+	return nil, lastErr
+}
+
+// send delivers req to a single provider and records the outcome. An error
+// response caused by the endpoint is returned together with its error.
+func (t *poolTransport) send(ctx context.Context, p *provider, req *JSONRPCRequest) (*JSONRPCResponse, error) {
+	start := time.Now()
+	resp, err := p.transport.roundTrip(ctx, req)
+	if err == nil {
+		err = providerFault(req.Method, resp)
+	}
+	t.record(p, time.Since(start), err)
+	return resp, err
+}
+
+// providerFault returns the error of resp if it reflects the state of the
+// endpoint rather than the call: a rate limit, a block the endpoint has not
+// reached yet or an internal error, which another endpoint may not have.
+// Reverts, invalid params and the like are the same on every endpoint.
+func providerFault(method string, resp *JSONRPCResponse) error {
+	if resp.Error == nil {
+		return nil
+	}
+	err := rpcError(method, resp.Error)
+	if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrBlockNotFound) || resp.Error.Code == codeInternalError {
+		return err
+	}
+	return nil
+}
+
+// batchFault returns the first error in resps caused by the endpoint
+func batchFault(reqs []*JSONRPCRequest, resps []*JSONRPCResponse) error {
+	methods := make(map[int]string, len(reqs))
+	for _, req := range reqs {
+		methods[req.ID] = req.Method
+	}
+	for _, resp := range resps {
+		if err := providerFault(methods[resp.ID], resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterProvider returns the provider that installed the filter req
+// refers to, if any
+func (t *poolTransport) filterProvider(req *JSONRPCRequest) *provider {
+	switch req.Method {
+	case "eth_getFilterChanges", "eth_getFilterLogs", "eth_uninstallFilter":
+	default:
+		return nil
+	}
+	if len(req.Params) == 0 {
+		return nil
+	}
+	id, _ := req.Params[0].(string)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.filters[id]
+	if req.Method == "eth_uninstallFilter" {
+		delete(t.filters, id)
+	}
+	return p
+}
+
+// trackFilter remembers which provider installed a new filter
+func (t *poolTransport) trackFilter(p *provider, req *JSONRPCRequest, resp *JSONRPCResponse) {
+	switch req.Method {
+	case "eth_newFilter", "eth_newBlockFilter", "eth_newPendingTransactionFilter":
+	default:
+		return
+	}
+	var id string
+	if resp.Error != nil || json.Unmarshal(resp.Result, &id) != nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.filters[id] = p
+	t.mu.Unlock()
+}
+
+// probeResult is the answer of one provider to a head probe
+type probeResult struct {
+	p    *provider
+	resp *JSONRPCResponse
+	head int64
+	err  error
+}
+
+// probeHeads asks every provider that is not on probation for its block
+// number, ejects the providers that trail the highest head, re-admits those
+// whose probation is over and that have caught up, and answers req with the
+// head of the healthiest remaining provider
+func (t *poolTransport) probeHeads(ctx context.Context, req *JSONRPCRequest) (*JSONRPCResponse, error) {
+	t.mu.Lock()
+	now := time.Now()
+	var probed []*provider
+	for _, p := range t.providers {
+		if !now.Before(p.ejectedUntil) {
+			probed = append(probed, p)
+		}
+	}
+	// Probe everyone rather than no one when all endpoints are ejected
+	if len(probed) == 0 {
+		probed = t.providers
+	}
+	t.mu.Unlock()
+
+	probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	results := make(chan probeResult, len(probed))
+	for _, p := range probed {
+		go func(p *provider) {
+			resp, err := t.send(probeCtx, p, req)
+			result := probeResult{p: p, resp: resp, err: err}
+			if err == nil && resp.Error != nil {
+				result.err = rpcError(req.Method, resp.Error)
+			}
+			if result.err == nil {
+				var hexHead string
+				if err := json.Unmarshal(resp.Result, &hexHead); err != nil {
+					result.err = fmt.Errorf("failed to parse block number: %v", err)
+				} else if head, err := parseHexUint64(hexHead); err != nil {
+					result.err = fmt.Errorf("failed to convert block number: %v", err)
+				} else {
+					result.head = int64(head)
+				}
+			}
+			results <- result
+		}(p)
+	}
+
+	collected := make([]probeResult, 0, len(probed))
+	for range probed {
+		collected = append(collected, <-results)
+	}
+
+	answers := make(map[*provider]*JSONRPCResponse, len(probed))
+	var lastErr error
+
+	t.mu.Lock()
+	for _, result := range collected {
+		if result.err != nil {
+			t.eject(result.p, fmt.Sprintf("head probe failed: %v", result.err))
+			lastErr = result.err
+			continue
+		}
+		result.p.head = result.head
+		t.head = max(t.head, result.head)
+		answers[result.p] = result.resp
+	}
+	for p := range answers {
+		if lag := t.head - p.head; lag > t.cfg.MaxHeadLag {
+			t.eject(p, fmt.Sprintf("%d blocks behind head %d", lag, t.head))
+		} else if !p.ejectedUntil.IsZero() {
+			log.Printf("RPC endpoint %s is back in rotation at block %d", p.name, p.head)
+			p.ejectedUntil = time.Time{}
+		}
+	}
+	t.mu.Unlock()
+
+	// When every endpoint trails the head, ranked falls back to the ejected
+	// ones and the healthiest of them still answers
+	for _, p := range t.ranked() {
+		if resp, ok := answers[p]; ok {
+			return resp, nil
+		}
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no RPC endpoint available")
+	}
+	return nil, lastErr
+}
+
+// eject takes p out of rotation for the probation period. The caller holds
+// t.mu.
+func (t *poolTransport) eject(p *provider, reason string) {
+	if time.Now().Before(p.ejectedUntil) {
+		return
+	}
+	log.Printf("Ejecting RPC endpoint %s for %s: %s", p.name, t.cfg.Probation, reason)
+	p.ejectedUntil = time.Now().Add(t.cfg.Probation)
+}
+
+// stream returns the healthiest provider's persistent connection, for
+// subscriptions
+func (t *poolTransport) stream() *streamTransport {
+	for _, p := range t.ranked() {
+		if stream := streamOf(p.transport); stream != nil {
+			return stream
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// poolNode serves eth_blockNumber at an adjustable head and counts the
+// eth_getBalance calls it answers, failing them with balanceErr if set
+type poolNode struct {
+	server     *httptest.Server
+	head       atomic.Int64
+	balances   atomic.Int32
+	balanceErr atomic.Pointer[RPCError]
+}
+
+func newPoolNode(t *testing.T, head int64) *poolNode {
+	node := &poolNode{}
+	node.head.Store(head)
+	node.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req JSONRPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		result := "0x1"
+		switch req.Method {
+		case "eth_blockNumber":
+			result = fmt.Sprintf("0x%x", node.head.Load())
+		case "eth_getBalance":
+			node.balances.Add(1)
+			if rpcErr := node.balanceErr.Load(); rpcErr != nil {
+				json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "error": rpcErr})
+				return
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	t.Cleanup(node.server.Close)
+	return node
+}
+
+func TestPoolEjectsLaggingEndpoints(t *testing.T) {
+	lagging := newPoolNode(t, 10)
+	synced := newPoolNode(t, 100)
+
+	probation := 50 * time.Millisecond
+	client, err := NewPoolClient([]Endpoint{
+		{URL: lagging.server.URL, Weight: 100},
+		{URL: synced.server.URL, Weight: 1},
+	}, PoolConfig{MaxHeadLag: 5, Probation: probation})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	head, err := client.GetBlockNumber(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if head != 100 {
+		t.Errorf("Expected head 100 from the synced endpoint, got %d", head)
+	}
+
+	// The preferred endpoint is out of rotation while it lags
+	if _, err := client.GetBalance(context.Background(), types.Address("0x1")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if lagging.balances.Load() != 0 || synced.balances.Load() != 1 {
+		t.Fatalf("Expected the synced endpoint to serve the call, got %d lagging and %d synced",
+			lagging.balances.Load(), synced.balances.Load())
+	}
+
+	// After probation it is probed again and re-admitted once caught up
+	lagging.head.Store(100)
+	time.Sleep(2 * probation)
+	if _, err := client.GetBlockNumber(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := client.GetBalance(context.Background(), types.Address("0x1")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if lagging.balances.Load() != 1 {
+		t.Fatalf("Expected the re-admitted endpoint to serve the call")
+	}
+
+	// Calls fail over when the preferred endpoint goes down
+	lagging.server.Close()
+	if _, err := client.GetBalance(context.Background(), types.Address("0x1")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if synced.balances.Load() != 2 {
+		t.Errorf("Expected the call to fail over to the synced endpoint")
+	}
+}
+
+func TestPoolFailsOverOnProviderErrors(t *testing.T) {
+	tests := []struct {
+		err      RPCError
+		failover bool
+	}{
+		{RPCError{Code: codeLimitExceeded, Message: "request limit reached"}, true},
+		{RPCError{Code: -32000, Message: "header not found"}, true},
+		{RPCError{Code: codeInternalError, Message: "internal error"}, true},
+		{RPCError{Code: codeInvalidParams, Message: "invalid argument 0"}, false},
+		{RPCError{Code: codeExecutionReverted, Message: "execution reverted"}, false},
+	}
+
+	for _, tt := range tests {
+		preferred := newPoolNode(t, 100)
+		other := newPoolNode(t, 100)
+		preferred.balanceErr.Store(&tt.err)
+
+		client, err := NewPoolClient([]Endpoint{
+			{URL: preferred.server.URL, Weight: 100},
+			{URL: other.server.URL, Weight: 1},
+		}, PoolConfig{MaxHeadLag: 5, Probation: time.Minute})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		_, err = client.GetBalance(context.Background(), types.Address("0x1"))
+		if tt.failover && (err != nil || other.balances.Load() != 1) {
+			t.Errorf("%s: expected the call to fail over, got %v", tt.err.Message, err)
+		}
+		if !tt.failover && (err == nil || other.balances.Load() != 0) {
+			t.Errorf("%s: expected the error without failing over, got %v", tt.err.Message, err)
+		}
+
+		pool := client.transport.(*poolTransport)
+		failed := pool.providers[0].errorRate > 0
+		if failed != tt.failover {
+			t.Errorf("%s: expected the error to count against the endpoint: %v", tt.err.Message, tt.failover)
+		}
+	}
+}
+
+func TestPoolReturnsProviderErrorWhenAllRefuse(t *testing.T) {
+	a := newPoolNode(t, 100)
+	b := newPoolNode(t, 100)
+	for _, node := range []*poolNode{a, b} {
+		node.balanceErr.Store(&RPCError{Code: -32000, Message: "header not found"})
+	}
+
+	client, err := NewPoolClient([]Endpoint{{URL: a.server.URL}, {URL: b.server.URL}}, PoolConfig{MaxHeadLag: 5, Probation: time.Minute})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, err = client.GetBalance(context.Background(), types.Address("0x1"))
+	if !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("Expected ErrBlockNotFound, got %v", err)
+	}
+}
+
+func TestPoolProbesEndpointsBeforeReadmission(t *testing.T) {
+	lagging := newPoolNode(t, 10)
+	synced := newPoolNode(t, 100)
+
+	probation := 20 * time.Millisecond
+	client, err := NewPoolClient([]Endpoint{
+		{URL: lagging.server.URL, Weight: 100},
+		{URL: synced.server.URL, Weight: 1},
+	}, PoolConfig{MaxHeadLag: 5, Probation: probation})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := client.GetBlockNumber(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Probation running out alone does not put the endpoint back
+	time.Sleep(2 * probation)
+	if _, err := client.GetBalance(context.Background(), types.Address("0x1")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if lagging.balances.Load() != 0 {
+		t.Fatalf("Expected the lagging endpoint to stay out of rotation until probed")
+	}
+
+	// A probe that finds it still lagging extends its probation
+	if _, err := client.GetBlockNumber(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pool := client.transport.(*poolTransport)
+	pool.mu.Lock()
+	extended := time.Now().Before(pool.providers[0].ejectedUntil)
+	pool.mu.Unlock()
+	if !extended {
+		t.Errorf("Expected the probation of the lagging endpoint to be extended")
+	}
+	if _, err := client.GetBalance(context.Background(), types.Address("0x1")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if lagging.balances.Load() != 0 {
+		t.Errorf("Expected the lagging endpoint to stay out of rotation")
+	}
+}
@@ -0,0 +1,189 @@
+package ethereum
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// ErrQuorumDisagreement is returned by VerifyBlock when the endpoints of a
+// quorum disagree about a block
+var ErrQuorumDisagreement = errors.New("RPC providers disagree")
+
+// VerifyBlock fetches the hash of block and the transactions in txHashes
+// from a quorum of the pool's endpoints and compares every answer with
+// block. Mismatches are returned as disagreements together with
+// ErrQuorumDisagreement. It does nothing unless quorum mode is enabled.
+func (c *Client) VerifyBlock(ctx context.Context, block *types.Block, txHashes []string) ([]types.Disagreement, error) {
+	pool, ok := c.transport.(*poolTransport)
+	if !ok || pool.cfg.Quorum < 2 {
+		return nil, nil
+	}
+
+	expected := map[string]string{types.DisagreementBlock: block.Hash}
+	wanted := make(map[string]bool, len(txHashes))
+	for _, hash := range txHashes {
+		wanted[hash] = true
+	}
+	for _, tx := range block.Transactions {
+		if wanted[tx.Hash] {
+			expected[tx.Hash] = quorumSummary(tx)
+		}
+	}
+
+	answers, err := pool.collectQuorum(ctx, block.Number, txHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	// The transactions of different blocks differ anyway, so only the
+	// block is reported when the hashes disagree
+	subjects := []string{types.DisagreementBlock}
+	if d := compareAnswers(block.Number, types.DisagreementBlock, expected, answers); d == nil {
+		subjects = txHashes
+	}
+
+	var disagreements []types.Disagreement
+	for _, subject := range subjects {
+		if d := compareAnswers(block.Number, subject, expected, answers); d != nil {
+			disagreements = append(disagreements, *d)
+		}
+	}
+	if len(disagreements) > 0 {
+		return disagreements, fmt.Errorf("%w on block %d", ErrQuorumDisagreement, block.Number)
+	}
+	return nil, nil
+}
+
+// compareAnswers returns a disagreement if any endpoint's value for subject
+// differs from the expected one
+func compareAnswers(blockNumber int64, subject string, expected map[string]string, answers map[string]map[string]string) *types.Disagreement {
+	agreed := true
+	values := make(map[string]string, len(answers))
+	for name, answer := range answers {
+		values[name] = answer[subject]
+		if answer[subject] != expected[subject] {
+			agreed = false
+		}
+	}
+	if agreed {
+		return nil
+	}
+
+	now := time.Now().Unix()
+	return &types.Disagreement{
+		BlockNumber: blockNumber,
+		Subject:     subject,
+		Expected:    expected[subject],
+		Answers:     values,
+		FirstSeen:   now,
+		LastSeen:    now,
+	}
+}
+
+// collectQuorum fetches the values compared by VerifyBlock from Quorum
+// endpoints, healthiest first, asking further endpoints in place of those
+// that fail. The answers are keyed by endpoint name.
+func (t *poolTransport) collectQuorum(ctx context.Context, blockNumber int64, txHashes []string) (map[string]map[string]string, error) {
+	active, ejected := t.ordered()
+	candidates := append(active, ejected...)
+
+	type result struct {
+		p      *provider
+		values map[string]string
+		err    error
+	}
+
+	answers := make(map[string]map[string]string, t.cfg.Quorum)
+	var lastErr error
+	for len(answers) < t.cfg.Quorum && len(candidates) > 0 {
+		wave := candidates[:min(t.cfg.Quorum-len(answers), len(candidates))]
+		candidates = candidates[len(wave):]
+
+		results := make(chan result, len(wave))
+		for _, p := range wave {
+			go func(p *provider) {
+				values, err := p.client.quorumValues(ctx, blockNumber, txHashes)
+				results <- result{p: p, values: values, err: err}
+			}(p)
+		}
+		for range wave {
+			r := <-results
+			if r.err != nil {
+				log.Printf("RPC endpoint %s failed the quorum check of block %d: %v", r.p.name, blockNumber, r.err)
+				lastErr = r.err
+				continue
+			}
+			answers[r.p.name] = r.values
+		}
+	}
+
+	if len(answers) < t.cfg.Quorum {
+		return nil, fmt.Errorf("quorum of %d not reached for block %d, %d endpoints answered: %v",
+			t.cfg.Quorum, blockNumber, len(answers), lastErr)
+	}
+	return answers, nil
+}
+
+// quorumValues fetches the hash of a block and a summary of each of the
+// given transactions, keyed like the subjects of a disagreement
+func (c *Client) quorumValues(ctx context.Context, blockNumber int64, txHashes []string) (map[string]string, error) {
+	var header rpcBlockHeader
+	txs := make([]*rpcTransaction, len(txHashes))
+
+	calls := []BatchElem{{
+		Method: "eth_getBlockByNumber",
+		Params: []interface{}{fmt.Sprintf("0x%x", blockNumber), false},
+		Result: &header,
+	}}
+	for i, hash := range txHashes {
+		calls = append(calls, BatchElem{
+			Method: "eth_getTransactionByHash",
+			Params: []interface{}{hash},
+			Result: &txs[i],
+		})
+	}
+
+	if err := c.BatchCall(ctx, calls); err != nil {
+		return nil, err
+	}
+	for _, call := range calls {
+		if call.Error != nil {
+			return nil, call.Error
+		}
+	}
+	// An endpoint that has not seen the block yet cannot vote on it
+	if header.Hash == "" {
+		return nil, fmt.Errorf("%w: %d", ErrBlockNotFound, blockNumber)
+	}
+
+	values := map[string]string{types.DisagreementBlock: header.Hash}
+	for i, hash := range txHashes {
+		if txs[i] == nil {
+			values[hash] = "not found"
+			continue
+		}
+		tx, err := txs[i].toTransaction()
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: %v", hash, err)
+		}
+		values[hash] = quorumSummary(tx)
+	}
+	return values, nil
+}
+
+// quorumSummary renders the fields of a transaction that endpoints must
+// agree on. The input is hashed to keep the summary short.
+func quorumSummary(tx types.Transaction) string {
+	value := "0"
+	if tx.Value != nil {
+		value = tx.Value.String()
+	}
+	return fmt.Sprintf("block=%s index=%d from=%s to=%s value=%s nonce=%d input=sha256:%x",
+		tx.BlockHash, tx.TransactionIndex, tx.From, tx.To, value, tx.Nonce, sha256.Sum256([]byte(tx.Input)))
+}
@@ -0,0 +1,144 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// newBlockNode answers batches of eth_getBlockByNumber with a block of the
+// given hash and of eth_getTransactionByHash with txs, or null for unknown
+// transactions
+func newBlockNode(t *testing.T, hash string, txs ...map[string]string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqs []JSONRPCRequest
+		json.NewDecoder(r.Body).Decode(&reqs)
+
+		var resps []map[string]interface{}
+		for _, req := range reqs {
+			var result interface{} = map[string]string{"number": "0x5", "hash": hash}
+			if req.Method == "eth_getTransactionByHash" {
+				result = nil
+				for _, tx := range txs {
+					if tx["hash"] == req.Params[0] {
+						result = tx
+					}
+				}
+			}
+			resps = append(resps, map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		}
+		json.NewEncoder(w).Encode(resps)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestVerifyBlockReportsDisagreements(t *testing.T) {
+	block := &types.Block{BlockHeader: types.BlockHeader{Number: 5, Hash: "0xaaa"}}
+	endpoints := []Endpoint{
+		{URL: newBlockNode(t, "0xaaa"), Weight: 3},
+		{URL: newBlockNode(t, "0xaaa"), Weight: 2},
+		{URL: newBlockNode(t, "0xbbb"), Weight: 1},
+	}
+
+	// The two preferred endpoints agree
+	client, err := NewPoolClient(endpoints, PoolConfig{Quorum: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if disagreements, err := client.VerifyBlock(context.Background(), block, nil); err != nil || len(disagreements) != 0 {
+		t.Fatalf("Expected agreement, got %v, %v", disagreements, err)
+	}
+
+	// All three must agree
+	client, err = NewPoolClient(endpoints, PoolConfig{Quorum: 3})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	disagreements, err := client.VerifyBlock(context.Background(), block, nil)
+	if !errors.Is(err, ErrQuorumDisagreement) {
+		t.Fatalf("Expected ErrQuorumDisagreement, got %v", err)
+	}
+	if len(disagreements) != 1 {
+		t.Fatalf("Expected 1 disagreement, got %d", len(disagreements))
+	}
+	d := disagreements[0]
+	if d.Subject != types.DisagreementBlock || d.Expected != "0xaaa" || len(d.Answers) != 3 {
+		t.Errorf("Unexpected disagreement: %+v", d)
+	}
+
+	if _, err := NewPoolClient(endpoints, PoolConfig{Quorum: 4}); err == nil {
+		t.Errorf("Expected an error for a quorum larger than the pool")
+	}
+}
+
+func TestVerifyBlockComparesTransactions(t *testing.T) {
+	rawTx := func(value, to string) map[string]string {
+		return map[string]string{
+			"hash": "0x01", "type": "0x2", "from": "0x" + strings.Repeat("11", 20), "to": to,
+			"value": value, "nonce": "0x0", "gas": "0x5208", "input": "0x",
+			"blockNumber": "0x5", "blockHash": "0xaaa", "transactionIndex": "0x0",
+		}
+	}
+	recipient := "0x" + strings.Repeat("22", 20)
+	good := rawTx("0x3e8", recipient)
+
+	// The block as indexed holds the transaction as the first endpoint reports it
+	data, _ := json.Marshal(good)
+	var raw rpcTransaction
+	json.Unmarshal(data, &raw)
+	tx, err := raw.toTransaction()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	block := &types.Block{BlockHeader: types.BlockHeader{Number: 5, Hash: "0xaaa"}, Transactions: []types.Transaction{tx}}
+
+	tests := []struct {
+		name   string
+		other  []map[string]string
+		answer string
+	}{
+		{"different value", []map[string]string{rawTx("0x3e9", recipient)}, "value=1001"},
+		{"different recipient", []map[string]string{rawTx("0x3e8", "0x"+strings.Repeat("33", 20))}, "to=0x" + strings.Repeat("33", 20)},
+		{"not found", nil, "not found"},
+	}
+
+	for _, tt := range tests {
+		endpoints := []Endpoint{
+			{URL: newBlockNode(t, "0xaaa", good), Weight: 2},
+			{URL: newBlockNode(t, "0xaaa", tt.other...), Weight: 1},
+		}
+		client, err := NewPoolClient(endpoints, PoolConfig{Quorum: 2})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		disagreements, err := client.VerifyBlock(context.Background(), block, []string{"0x01"})
+		if !errors.Is(err, ErrQuorumDisagreement) {
+			t.Fatalf("%s: expected ErrQuorumDisagreement, got %v", tt.name, err)
+		}
+		if len(disagreements) != 1 || disagreements[0].Subject != "0x01" {
+			t.Fatalf("%s: expected a disagreement on the transaction, got %+v", tt.name, disagreements)
+		}
+
+		d := disagreements[0]
+		var matched, differs int
+		for _, answer := range d.Answers {
+			switch {
+			case answer == d.Expected:
+				matched++
+			case strings.Contains(answer, tt.answer):
+				differs++
+			}
+		}
+		if matched != 1 || differs != 1 {
+			t.Errorf("%s: expected one matching and one differing answer, got %v", tt.name, d.Answers)
+		}
+	}
+}
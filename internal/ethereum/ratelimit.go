@@ -0,0 +1,94 @@
+package ethereum
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenBucket limits the rate of calls to an endpoint while allowing short
+// bursts
+type tokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket allows rate calls per second on average and up to burst
+// calls at once
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	burst = max(burst, 1)
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until n calls may be made or ctx is done. Requests for more
+// than the burst only wait for a full bucket.
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+
+		need := min(float64(n), b.burst)
+		if b.tokens >= need {
+			b.tokens -= need
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((need - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// limitedTransport throttles the calls sent through another transport.
+// Every call of a batch counts against the limit, as providers bill them
+// individually.
+type limitedTransport struct {
+	next    transport
+	limiter *tokenBucket
+}
+
+func (t *limitedTransport) roundTrip(ctx context.Context, req *JSONRPCRequest) (*JSONRPCResponse, error) {
+	if err := t.limiter.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	return t.next.roundTrip(ctx, req)
+}
+
+func (t *limitedTransport) batchRoundTrip(ctx context.Context, reqs []*JSONRPCRequest) ([]*JSONRPCResponse, error) {
+	if err := t.limiter.wait(ctx, len(reqs)); err != nil {
+		return nil, err
+	}
+	return t.next.batchRoundTrip(ctx, reqs)
+}
+
+// streamOf returns the persistent connection behind t, or nil if t has
+// none
+func streamOf(t transport) *streamTransport {
+	switch t := t.(type) {
+	case *streamTransport:
+		return t
+	case *limitedTransport:
+		return streamOf(t.next)
+	case *poolTransport:
+		return t.stream()
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// rpcReceipt is the JSON-RPC representation of a transaction receipt
+type rpcReceipt struct {
+	TransactionHash   string  `json:"transactionHash"`
+	Status            string  `json:"status"`
+	GasUsed           string  `json:"gasUsed"`
+	CumulativeGasUsed string  `json:"cumulativeGasUsed"`
+	EffectiveGasPrice string  `json:"effectiveGasPrice"`
+	ContractAddress   *string `json:"contractAddress"`
+	BlobGasUsed       string  `json:"blobGasUsed"`
+	BlobGasPrice      string  `json:"blobGasPrice"`
+}
+
+func (r rpcReceipt) toReceipt() (types.Receipt, error) {
+	receipt := types.Receipt{TransactionHash: r.TransactionHash}
+	var err error
+
+	// Pre-Byzantium receipts carry a state root instead of a status
+	switch r.Status {
+	case "0x1":
+		receipt.Status = types.ReceiptSuccess
+	case "0x0":
+		receipt.Status = types.ReceiptFailed
+	}
+
+	if receipt.GasUsed, err = parseHexUint64(r.GasUsed); err != nil {
+		return receipt, fmt.Errorf("gasUsed: %v", err)
+	}
+	if receipt.CumulativeGasUsed, err = parseHexUint64(r.CumulativeGasUsed); err != nil {
+		return receipt, fmt.Errorf("cumulativeGasUsed: %v", err)
+	}
+	if r.EffectiveGasPrice != "" {
+		if receipt.EffectiveGasPrice, err = parseHexBig(r.EffectiveGasPrice); err != nil {
+			return receipt, fmt.Errorf("effectiveGasPrice: %v", err)
+		}
+	}
+	if r.ContractAddress != nil {
+		if receipt.ContractAddress, err = types.ParseAddress(*r.ContractAddress); err != nil {
+			return receipt, fmt.Errorf("contractAddress: %v", err)
+		}
+	}
+
+	if r.BlobGasUsed != "" && r.BlobGasPrice != "" {
+		blobGasUsed, err := parseHexBig(r.BlobGasUsed)
+		if err != nil {
+			return receipt, fmt.Errorf("blobGasUsed: %v", err)
+		}
+		blobGasPrice, err := parseHexBig(r.BlobGasPrice)
+		if err != nil {
+			return receipt, fmt.Errorf("blobGasPrice: %v", err)
+		}
+		receipt.BlobFee = new(big.Int).Mul(blobGasUsed, blobGasPrice)
+	}
+
+	return receipt, nil
+}
+
+// GetReceipts retrieves the receipts of the given transactions of a block,
+// keyed by transaction hash. It uses a single eth_getBlockReceipts call when
+// the node supports it and falls back to batched eth_getTransactionReceipt
+// calls otherwise.
+func (c *Client) GetReceipts(ctx context.Context, blockNumber int64, txHashes []string) (map[string]types.Receipt, error) {
+	receipts := make(map[string]types.Receipt, len(txHashes))
+	if len(txHashes) == 0 {
+		return receipts, nil
+	}
+
+	if !c.blockReceiptsUnsupported.Load() {
+		blockReceipts, err := c.GetBlockReceipts(ctx, blockNumber)
+		switch {
+		case err == nil:
+			wanted := make(map[string]bool, len(txHashes))
+			for _, hash := range txHashes {
+				wanted[hash] = true
+			}
+			for _, receipt := range blockReceipts {
+				if wanted[receipt.TransactionHash] {
+					receipts[receipt.TransactionHash] = receipt
+				}
+			}
+			return receipts, nil
+		case errors.Is(err, ErrMethodNotFound):
+			log.Printf("eth_getBlockReceipts is not supported, falling back to eth_getTransactionReceipt")
+			c.blockReceiptsUnsupported.Store(true)
+		default:
+			return nil, err
+		}
+	}
+
+	raw := make([]*rpcReceipt, len(txHashes))
+	calls := make([]BatchElem, len(txHashes))
+	for i, hash := range txHashes {
+		calls[i] = BatchElem{
+			Method: "eth_getTransactionReceipt",
+			Params: []interface{}{hash},
+			Result: &raw[i],
+		}
+	}
+	if err := c.BatchCall(ctx, calls); err != nil {
+		return nil, fmt.Errorf("failed to fetch receipts: %w", err)
+	}
+
+	for i, hash := range txHashes {
+		if calls[i].Error != nil {
+			return nil, fmt.Errorf("failed to fetch receipt of %s: %w", hash, calls[i].Error)
+		}
+		if raw[i] == nil {
+			return nil, fmt.Errorf("receipt of %s not found", hash)
+		}
+		receipt, err := raw[i].toReceipt()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse receipt of %s: %v", hash, err)
+		}
+		receipts[hash] = receipt
+	}
+	return receipts, nil
+}
+
+// GetBlockReceipts retrieves the receipts of every transaction in a block
+func (c *Client) GetBlockReceipts(ctx context.Context, blockNumber int64) ([]types.Receipt, error) {
+	resp, err := c.makeJSONRPCRequest(ctx, "eth_getBlockReceipts",
+		[]interface{}{fmt.Sprintf("0x%x", blockNumber)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch block receipts: %w", err)
+	}
+
+	var raw []rpcReceipt
+	if err := json.Unmarshal(resp.Result, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse block receipts: %v", err)
+	}
+
+	receipts := make([]types.Receipt, 0, len(raw))
+	for _, r := range raw {
+		receipt, err := r.toReceipt()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse receipt of %s: %v", r.TransactionHash, err)
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
+
+// GetTransactionReceipt retrieves the receipt of a single transaction
+func (c *Client) GetTransactionReceipt(ctx context.Context, txHash string) (*types.Receipt, error) {
+	resp, err := c.makeJSONRPCRequest(ctx, "eth_getTransactionReceipt", []interface{}{txHash})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch receipt of %s: %w", txHash, err)
+	}
+
+	var raw *rpcReceipt
+	if err := json.Unmarshal(resp.Result, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse receipt of %s: %v", txHash, err)
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("receipt of %s not found", txHash)
+	}
+
+	receipt, err := raw.toReceipt()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse receipt of %s: %v", txHash, err)
+	}
+	return &receipt, nil
+}
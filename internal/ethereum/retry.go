@@ -0,0 +1,107 @@
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// retryBaseDelay is the backoff before the first retry; it doubles
+	// with every further attempt up to retryMaxDelay
+	retryBaseDelay = 250 * time.Millisecond
+	retryMaxDelay  = 10 * time.Second
+	// maxRetries bounds the retries of calls whose context has no deadline
+	maxRetries = 4
+)
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an
+// HTTP date
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
+
+// classify reports whether a failed call may succeed when retried, and the
+// minimum delay the endpoint asked for
+func classify(err error) (retryable bool, after time.Duration) {
+	var httpErr *HTTPError
+	var rpcErr *RPCError
+
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false, 0
+	case errors.Is(err, errRequestTimeout):
+		// Only this attempt timed out; the caller still has time left
+		return true, 0
+	case errors.Is(err, errBatchTooLarge):
+		return false, 0
+	case errors.As(err, &httpErr):
+		switch {
+		case httpErr.StatusCode == http.StatusTooManyRequests,
+			httpErr.StatusCode == http.StatusRequestTimeout,
+			httpErr.StatusCode >= 500 && httpErr.StatusCode != http.StatusNotImplemented:
+			return true, httpErr.RetryAfter
+		}
+		return false, 0
+	case errors.As(err, &rpcErr):
+		return errors.Is(rpcErr, ErrRateLimited), 0
+	}
+	// Anything else failed on the way to or from the node
+	return true, 0
+}
+
+// backoff returns the jittered delay before retry number attempt
+func backoff(attempt int) time.Duration {
+	delay := retryMaxDelay
+	if attempt < 16 {
+		delay = min(retryBaseDelay<<attempt, retryMaxDelay)
+	}
+	// Spread retries of concurrent calls over the upper half of the delay
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retry runs call until it succeeds or fails with an error that is not
+// retryable, waiting with exponential backoff in between. Retries stop
+// when the next one could not start before ctx's deadline, or after
+// maxRetries when ctx has none.
+func (c *Client) retry(ctx context.Context, method string, call func() error) error {
+	deadline, hasDeadline := ctx.Deadline()
+
+	for attempt := 0; ; attempt++ {
+		err := call()
+		if err == nil {
+			return nil
+		}
+
+		retryable, after := classify(err)
+		if !retryable || (!hasDeadline && attempt >= maxRetries) {
+			return err
+		}
+		delay := max(backoff(attempt), after)
+		if hasDeadline && time.Now().Add(delay).After(deadline) {
+			return err
+		}
+
+		log.Printf("%s failed, retrying in %s: %v", method, delay.Round(time.Millisecond), err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyNode answers eth_blockNumber with the given responses in turn and
+// succeeds once they are used up. A response is an HTTP status, or a
+// JSON-RPC error code when negative.
+func newFlakyNode(t *testing.T, retryAfter string, responses ...int) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req JSONRPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		n := int(calls.Add(1))
+		if n > len(responses) {
+			json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": "0x64"})
+			return
+		}
+
+		if code := responses[n-1]; code < 0 {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"jsonrpc": "2.0", "id": req.ID,
+				"error": map[string]interface{}{"code": code, "message": "error"},
+			})
+			return
+		}
+		w.Header().Set("Retry-After", retryAfter)
+		w.WriteHeader(responses[n-1])
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestClientRetriesTransientErrors(t *testing.T) {
+	server, calls := newFlakyNode(t, "", http.StatusTooManyRequests, codeLimitExceeded, http.StatusBadGateway)
+	client, _ := NewClient(server.URL)
+
+	number, err := client.GetBlockNumber(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if number != 100 || calls.Load() != 4 {
+		t.Errorf("Expected block 100 after 4 calls, got %d after %d", number, calls.Load())
+	}
+
+	// Invalid params will not get better
+	server, calls = newFlakyNode(t, "", -32602)
+	client, _ = NewClient(server.URL)
+	if _, err := client.GetBlockNumber(context.Background()); !errors.Is(err, ErrInvalidParams) {
+		t.Fatalf("Expected invalid params, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected 1 call, got %d", calls.Load())
+	}
+
+	// Retry-After beyond the caller's deadline gives up right away
+	server, calls = newFlakyNode(t, "30", http.StatusTooManyRequests)
+	client, _ = NewClient(server.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.GetBlockHeader(ctx, 1); err == nil {
+		t.Fatalf("Expected an error")
+	}
+	if calls.Load() != 1 || time.Since(start) > time.Second {
+		t.Errorf("Expected a single call without waiting, got %d calls in %s", calls.Load(), time.Since(start))
+	}
+}
+
+func TestTokenBucketLimitsRate(t *testing.T) {
+	bucket := newTokenBucket(50, 2)
+
+	start := time.Now()
+	for range 6 {
+		if err := bucket.wait(context.Background(), 1); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// The burst of 2 passes at once, the other 4 calls wait 20ms each
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Errorf("Expected the calls to be throttled, took %s", elapsed)
+	}
+}
+
+// stallingConn ignores the first stalls requests it receives and answers
+// the rest with block 100
+type stallingConn struct {
+	stalls   int32
+	requests atomic.Int32
+	messages chan []byte
+}
+
+func (c *stallingConn) readMessage() ([]byte, error) {
+	return <-c.messages, nil
+}
+
+func (c *stallingConn) writeMessage(v interface{}) error {
+	if c.requests.Add(1) <= c.stalls {
+		return nil
+	}
+	req := v.(*JSONRPCRequest)
+	msg, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": "0x64"})
+	c.messages <- msg
+	return nil
+}
+
+func (c *stallingConn) close() error { return nil }
+
+func TestClientRetriesStalledStreamRequests(t *testing.T) {
+	conn := &stallingConn{stalls: 1, messages: make(chan []byte, 1)}
+	transport := newStreamTransport(func(context.Context) (streamConn, error) { return conn, nil })
+	transport.timeout = 50 * time.Millisecond
+	client := newClient(transport)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	number, err := client.GetBlockNumber(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if number != 100 || conn.requests.Load() != 2 {
+		t.Errorf("Expected block 100 on the second attempt, got %d after %d requests", number, conn.requests.Load())
+	}
+
+	// The caller's own deadline is not retried
+	conn.stalls = 10
+	short, cancelShort := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelShort()
+	if _, err := client.GetBlockNumber(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,362 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// errConnectionClosed is returned for requests and subscriptions that were
+// in flight when a persistent connection was lost
+var errConnectionClosed = errors.New("connection closed")
+
+// errRequestTimeout is returned when a request outlived the transport's
+// timeout while the caller's context is still live, so it can be retried
+var errRequestTimeout = errors.New("request timed out")
+
+// streamConn is a persistent, message-oriented connection to the node
+type streamConn interface {
+	// readMessage blocks until the next message arrives
+	readMessage() ([]byte, error)
+	// writeMessage sends v as a single JSON message. Calls are serialized
+	// by the transport.
+	writeMessage(v interface{}) error
+	close() error
+}
+
+// dialFunc opens a new connection to the node
+type dialFunc func(ctx context.Context) (streamConn, error)
+
+// streamResult is the outcome of a request or batch sent over a persistent
+// connection
+type streamResult struct {
+	resp  *JSONRPCResponse
+	batch []*JSONRPCResponse
+	err   error
+}
+
+// streamBatch is a batch awaiting its response, registered under the ID of
+// each of its requests since the node may answer with only some of them
+type streamBatch struct {
+	ids    []int
+	result chan streamResult
+}
+
+// streamMessage is any message received from the node: a response, or a
+// subscription notification when Method is set
+type streamMessage struct {
+	JSONRPCResponse
+	Method string `json:"method"`
+	Params struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+// streamTransport multiplexes requests and subscriptions over a single
+// persistent connection, such as a WebSocket or a Unix socket. The
+// connection is dialed on first use and redialed by the next request after
+// it is lost; subscriptions do not survive a reconnect and report the loss
+// through their Err channel.
+type streamTransport struct {
+	dial dialFunc
+	// timeout bounds a single request or batch
+	timeout time.Duration
+
+	mu      sync.Mutex
+	conn    streamConn
+	pending map[int]chan streamResult
+	batches map[int]*streamBatch
+	subs    map[string]*Subscription
+	// subscribing holds the subscriptions awaiting their eth_subscribe
+	// response, keyed by request ID
+	subscribing map[int]*Subscription
+
+	writeMu sync.Mutex
+}
+
+func newStreamTransport(dial dialFunc) *streamTransport {
+	return &streamTransport{
+		dial:        dial,
+		timeout:     requestTimeout,
+		pending:     make(map[int]chan streamResult),
+		batches:     make(map[int]*streamBatch),
+		subs:        make(map[string]*Subscription),
+		subscribing: make(map[int]*Subscription),
+	}
+}
+
+// connection returns the current connection, dialing a new one if needed
+func (t *streamTransport) connection(ctx context.Context) (streamConn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conn != nil {
+		return t.conn, nil
+	}
+
+	conn, err := t.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.conn = conn
+
+	go t.readLoop(conn)
+	return conn, nil
+}
+
+func (t *streamTransport) roundTrip(parent context.Context, req *JSONRPCRequest) (*JSONRPCResponse, error) {
+	ctx, cancel := context.WithTimeout(parent, t.timeout)
+	defer cancel()
+
+	conn, err := t.connection(ctx)
+	if err != nil {
+		return nil, t.timeoutError(parent, ctx, err)
+	}
+
+	result := make(chan streamResult, 1)
+	t.mu.Lock()
+	t.pending[req.ID] = result
+	t.mu.Unlock()
+
+	if err := t.write(conn, req); err != nil {
+		t.mu.Lock()
+		delete(t.pending, req.ID)
+		t.mu.Unlock()
+		return nil, err
+	}
+
+	select {
+	case res := <-result:
+		return res.resp, res.err
+	case <-ctx.Done():
+		t.mu.Lock()
+		delete(t.pending, req.ID)
+		t.mu.Unlock()
+		return nil, t.timeoutError(parent, ctx, ctx.Err())
+	}
+}
+
+func (t *streamTransport) batchRoundTrip(parent context.Context, reqs []*JSONRPCRequest) ([]*JSONRPCResponse, error) {
+	ctx, cancel := context.WithTimeout(parent, t.timeout)
+	defer cancel()
+
+	conn, err := t.connection(ctx)
+	if err != nil {
+		return nil, t.timeoutError(parent, ctx, err)
+	}
+
+	batch := &streamBatch{result: make(chan streamResult, 1)}
+	t.mu.Lock()
+	for _, req := range reqs {
+		batch.ids = append(batch.ids, req.ID)
+		t.batches[req.ID] = batch
+	}
+	t.mu.Unlock()
+
+	if err := t.write(conn, reqs); err != nil {
+		t.removeBatch(batch)
+		return nil, err
+	}
+
+	select {
+	case res := <-batch.result:
+		return res.batch, res.err
+	case <-ctx.Done():
+		t.removeBatch(batch)
+		return nil, t.timeoutError(parent, ctx, ctx.Err())
+	}
+}
+
+// timeoutError replaces err with errRequestTimeout when only the request
+// timeout in ctx expired and the caller's parent context is still live
+func (t *streamTransport) timeoutError(parent, ctx context.Context, err error) error {
+	if parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %v", errRequestTimeout, t.timeout)
+	}
+	return err
+}
+
+func (t *streamTransport) removeBatch(batch *streamBatch) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, id := range batch.ids {
+		delete(t.batches, id)
+	}
+}
+
+func (t *streamTransport) write(conn streamConn, v interface{}) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if err := conn.writeMessage(v); err != nil {
+		t.fail(conn, err)
+		return fmt.Errorf("failed to send request: %v", err)
+	}
+	return nil
+}
+
+// readLoop routes responses to their requests and notifications to their
+// subscriptions until the connection fails
+func (t *streamTransport) readLoop(conn streamConn) {
+	for {
+		data, err := conn.readMessage()
+		if err != nil {
+			t.fail(conn, err)
+			return
+		}
+
+		if isBatchResponse(data) {
+			t.deliverBatch(data)
+			continue
+		}
+
+		var msg streamMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+
+		t.mu.Lock()
+		if msg.Method == "eth_subscription" {
+			if sub, ok := t.subs[msg.Params.Subscription]; ok {
+				sub.deliver(msg.Params.Result)
+			}
+		} else if result, ok := t.pending[msg.ID]; ok {
+			// Register new subscriptions before reading on, as the node
+			// may send the first notification right after the response
+			if sub, ok := t.subscribing[msg.ID]; ok {
+				delete(t.subscribing, msg.ID)
+				if msg.Error == nil && json.Unmarshal(msg.Result, &sub.id) == nil {
+					t.subs[sub.id] = sub
+				}
+			}
+			delete(t.pending, msg.ID)
+			resp := msg.JSONRPCResponse
+			result <- streamResult{resp: &resp}
+		}
+		t.mu.Unlock()
+	}
+}
+
+// deliverBatch hands a batch response to the batch that contains its
+// first response
+func (t *streamTransport) deliverBatch(data []byte) {
+	resps, err := parseBatchResponse(data)
+	if err != nil || len(resps) == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	batch, ok := t.batches[resps[0].ID]
+	if !ok {
+		return
+	}
+	for _, id := range batch.ids {
+		delete(t.batches, id)
+	}
+	batch.result <- streamResult{batch: resps}
+}
+
+// fail discards a broken connection, failing every request and
+// subscription that depended on it
+func (t *streamTransport) fail(conn streamConn, cause error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conn != conn {
+		return
+	}
+	t.conn = nil
+	conn.close()
+
+	err := fmt.Errorf("%w: %v", errConnectionClosed, cause)
+	for id, result := range t.pending {
+		result <- streamResult{err: err}
+		delete(t.pending, id)
+	}
+	for id, batch := range t.batches {
+		delete(t.batches, id)
+		select {
+		case batch.result <- streamResult{err: err}:
+		default:
+		}
+	}
+	for id, sub := range t.subs {
+		sub.fail(err)
+		delete(t.subs, id)
+	}
+	for id := range t.subscribing {
+		delete(t.subscribing, id)
+	}
+}
+
+// subscribe sends an eth_subscribe request and registers deliver to
+// receive its notifications
+func (t *streamTransport) subscribe(ctx context.Context, req *JSONRPCRequest, deliver func(json.RawMessage)) (*Subscription, error) {
+	sub := &Subscription{
+		t:       t,
+		deliver: deliver,
+		err:     make(chan error, 1),
+	}
+
+	t.mu.Lock()
+	t.subscribing[req.ID] = sub
+	t.mu.Unlock()
+
+	resp, err := t.roundTrip(ctx, req)
+
+	t.mu.Lock()
+	delete(t.subscribing, req.ID)
+	_, registered := t.subs[sub.id]
+	t.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, rpcError(req.Method, resp.Error)
+	}
+	// The connection was lost right after the response
+	if !registered {
+		sub.fail(errConnectionClosed)
+	}
+	return sub, nil
+}
+
+// Subscription is an active eth_subscribe subscription
+type Subscription struct {
+	id      string
+	t       *streamTransport
+	deliver func(json.RawMessage)
+	err     chan error
+}
+
+// Err returns a channel that receives an error when the subscription ends
+// because the connection was lost
+func (s *Subscription) Err() <-chan error {
+	return s.err
+}
+
+// active removes the subscription from its transport and reports whether it
+// was still registered
+func (s *Subscription) active() bool {
+	s.t.mu.Lock()
+	defer s.t.mu.Unlock()
+
+	_, ok := s.t.subs[s.id]
+	delete(s.t.subs, s.id)
+	return ok
+}
+
+func (s *Subscription) fail(err error) {
+	select {
+	case s.err <- err:
+	default:
+	}
+}
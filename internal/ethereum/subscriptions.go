@@ -0,0 +1,65 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// ErrSubscriptionsUnsupported is returned by SubscribeNewHeads for HTTP
+// endpoints and endpoints that do not implement eth_subscribe. A pool
+// subscribes through its healthiest WebSocket or IPC endpoint.
+var ErrSubscriptionsUnsupported = errors.New("subscriptions are not supported by the RPC endpoint")
+
+// SubscribeNewHeads subscribes to the headers of new chain heads. Headers
+// are sent to heads without blocking and dropped when it is full, so heads
+// should be used as a signal that the chain advanced rather than as a
+// complete list of blocks.
+func (c *Client) SubscribeNewHeads(ctx context.Context, heads chan<- types.BlockHeader) (*Subscription, error) {
+	stream := streamOf(c.transport)
+	if stream == nil {
+		return nil, ErrSubscriptionsUnsupported
+	}
+
+	sub, err := stream.subscribe(ctx, c.newRequest("eth_subscribe", []interface{}{"newHeads"}), func(result json.RawMessage) {
+		var raw rpcBlockHeader
+		if err := json.Unmarshal(result, &raw); err != nil {
+			log.Printf("Failed to parse new head: %v", err)
+			return
+		}
+		header, err := raw.toHeader()
+		if err != nil {
+			log.Printf("Failed to parse new head %s: %v", raw.Hash, err)
+			return
+		}
+
+		select {
+		case heads <- header:
+		default:
+		}
+	})
+	if errors.Is(err, ErrMethodNotFound) {
+		return nil, fmt.Errorf("%w: %v", ErrSubscriptionsUnsupported, err)
+	}
+	return sub, err
+}
+
+// Unsubscribe cancels a subscription on the node
+func (c *Client) Unsubscribe(ctx context.Context, sub *Subscription) error {
+	if !sub.active() {
+		return nil
+	}
+	// The subscription only exists on the connection that created it
+	resp, err := sub.t.roundTrip(ctx, c.newRequest("eth_unsubscribe", []interface{}{sub.id}))
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return rpcError("eth_unsubscribe", resp.Error)
+	}
+	return nil
+}
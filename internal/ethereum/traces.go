@@ -0,0 +1,251 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// callFrame is a node of the call tree returned by geth's callTracer
+type callFrame struct {
+	Type  string      `json:"type"`
+	From  string      `json:"from"`
+	To    string      `json:"to"`
+	Value string      `json:"value"`
+	Error string      `json:"error"`
+	Calls []callFrame `json:"calls"`
+}
+
+// parityTrace is a single entry of a trace_block result
+type parityTrace struct {
+	Type   string `json:"type"`
+	Action struct {
+		CallType      string `json:"callType"`
+		From          string `json:"from"`
+		To            string `json:"to"`
+		Value         string `json:"value"`
+		Address       string `json:"address"`
+		RefundAddress string `json:"refundAddress"`
+		Balance       string `json:"balance"`
+	} `json:"action"`
+	Result *struct {
+		Address string `json:"address"`
+	} `json:"result"`
+	Error           string  `json:"error"`
+	TraceAddress    []int   `json:"traceAddress"`
+	TransactionHash *string `json:"transactionHash"`
+}
+
+// isValueTransfer reports whether a call of the given kind moves value from
+// its sender to its recipient. DELEGATECALL and CALLCODE run code in the
+// caller's context and STATICCALL cannot carry value.
+func isValueTransfer(callType string) bool {
+	switch callType {
+	case "call", "create", "create2", "selfdestruct":
+		return true
+	}
+	return false
+}
+
+// DebugTraceBlock extracts the value-bearing internal calls of a block using
+// debug_traceBlockByNumber with the callTracer. txHashes are the hashes of
+// the block's transactions in order, used when the node does not report the
+// hash of each trace. Calls that reverted, and every call below them, are
+// skipped.
+func (c *Client) DebugTraceBlock(ctx context.Context, blockNumber int64, txHashes []string) ([]types.InternalTransfer, error) {
+	resp, err := c.makeJSONRPCRequest(ctx, "debug_traceBlockByNumber", []interface{}{
+		fmt.Sprintf("0x%x", blockNumber),
+		map[string]string{"tracer": "callTracer"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to trace block: %w", err)
+	}
+
+	var results []struct {
+		TxHash string    `json:"txHash"`
+		Result callFrame `json:"result"`
+		Error  string    `json:"error"`
+	}
+	if err := json.Unmarshal(resp.Result, &results); err != nil {
+		return nil, fmt.Errorf("failed to parse block trace: %v", err)
+	}
+	if len(results) != len(txHashes) {
+		return nil, fmt.Errorf("got %d traces for %d transactions", len(results), len(txHashes))
+	}
+
+	var transfers []types.InternalTransfer
+	for i, result := range results {
+		txHash := result.TxHash
+		if txHash == "" {
+			txHash = txHashes[i]
+		}
+		if result.Error != "" {
+			return nil, fmt.Errorf("failed to trace transaction %s: %s", txHash, result.Error)
+		}
+		if result.Result.Error != "" {
+			continue
+		}
+
+		index := 0
+		for _, call := range result.Result.Calls {
+			if err := collectCallFrames(call, 1, txHash, blockNumber, &index, &transfers); err != nil {
+				return nil, fmt.Errorf("trace of transaction %s: %v", txHash, err)
+			}
+		}
+	}
+
+	return transfers, nil
+}
+
+// collectCallFrames appends the value transfers of frame and its successful
+// subcalls in depth-first order
+func collectCallFrames(frame callFrame, depth int, txHash string, blockNumber int64, index *int, transfers *[]types.InternalTransfer) error {
+	if frame.Error != "" {
+		return nil
+	}
+
+	callType := strings.ToLower(frame.Type)
+	if isValueTransfer(callType) && frame.Value != "" {
+		value, err := parseHexBig(frame.Value)
+		if err != nil {
+			return fmt.Errorf("value: %v", err)
+		}
+		if value.Sign() > 0 {
+			transfer, err := newInternalTransfer(txHash, blockNumber, depth, callType, frame.From, frame.To, value)
+			if err != nil {
+				return err
+			}
+			transfer.TraceIndex = *index
+			*index++
+			*transfers = append(*transfers, transfer)
+		}
+	}
+
+	for _, call := range frame.Calls {
+		if err := collectCallFrames(call, depth+1, txHash, blockNumber, index, transfers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TraceBlock extracts the value-bearing internal calls of a block using the
+// trace_block method of Erigon and Nethermind style nodes. Calls that
+// reverted, and every call below them, are skipped.
+func (c *Client) TraceBlock(ctx context.Context, blockNumber int64) ([]types.InternalTransfer, error) {
+	resp, err := c.makeJSONRPCRequest(ctx, "trace_block", []interface{}{fmt.Sprintf("0x%x", blockNumber)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to trace block: %w", err)
+	}
+
+	var traces []parityTrace
+	if err := json.Unmarshal(resp.Result, &traces); err != nil {
+		return nil, fmt.Errorf("failed to parse block trace: %v", err)
+	}
+
+	var transfers []types.InternalTransfer
+	var reverted [][]int
+	indexes := make(map[string]int)
+	for _, trace := range traces {
+		// Block and uncle rewards are not part of any transaction
+		if trace.TransactionHash == nil {
+			continue
+		}
+		txHash := *trace.TransactionHash
+
+		// Traces are ordered depth-first, so a reverted call is always seen
+		// before its subcalls
+		if len(trace.TraceAddress) == 0 {
+			reverted = reverted[:0]
+		}
+		if trace.Error != "" || hasRevertedAncestor(trace.TraceAddress, reverted) {
+			reverted = append(reverted, trace.TraceAddress)
+			continue
+		}
+		if len(trace.TraceAddress) == 0 {
+			continue
+		}
+
+		var callType, from, to, rawValue string
+		switch trace.Type {
+		case "call":
+			callType, from, to, rawValue = trace.Action.CallType, trace.Action.From, trace.Action.To, trace.Action.Value
+		case "create":
+			callType, from, rawValue = "create", trace.Action.From, trace.Action.Value
+			if trace.Result != nil {
+				to = trace.Result.Address
+			}
+		case "suicide", "selfdestruct":
+			callType, from, to, rawValue = "selfdestruct", trace.Action.Address, trace.Action.RefundAddress, trace.Action.Balance
+		default:
+			continue
+		}
+		if !isValueTransfer(callType) || rawValue == "" {
+			continue
+		}
+
+		value, err := parseHexBig(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("trace of transaction %s: value: %v", txHash, err)
+		}
+		if value.Sign() == 0 {
+			continue
+		}
+
+		transfer, err := newInternalTransfer(txHash, blockNumber, len(trace.TraceAddress), callType, from, to, value)
+		if err != nil {
+			return nil, fmt.Errorf("trace of transaction %s: %v", txHash, err)
+		}
+		transfer.TraceIndex = indexes[txHash]
+		indexes[txHash]++
+		transfers = append(transfers, transfer)
+	}
+
+	return transfers, nil
+}
+
+// hasRevertedAncestor reports whether traceAddress lies below one of the
+// reverted trace addresses
+func hasRevertedAncestor(traceAddress []int, reverted [][]int) bool {
+	for _, prefix := range reverted {
+		if len(prefix) >= len(traceAddress) {
+			continue
+		}
+		below := true
+		for i := range prefix {
+			if prefix[i] != traceAddress[i] {
+				below = false
+				break
+			}
+		}
+		if below {
+			return true
+		}
+	}
+	return false
+}
+
+func newInternalTransfer(txHash string, blockNumber int64, depth int, callType, from, to string, value *big.Int) (types.InternalTransfer, error) {
+	transfer := types.InternalTransfer{
+		TransactionHash: txHash,
+		Depth:           depth,
+		CallType:        callType,
+		BlockNumber:     blockNumber,
+		Value:           value,
+	}
+
+	var err error
+	if transfer.From, err = types.ParseAddress(from); err != nil {
+		return transfer, fmt.Errorf("from: %v", err)
+	}
+	if to != "" {
+		if transfer.To, err = types.ParseAddress(to); err != nil {
+			return transfer, fmt.Errorf("to: %v", err)
+		}
+	}
+	return transfer, nil
+}
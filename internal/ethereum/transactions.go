@@ -0,0 +1,141 @@
+package ethereum
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// rpcTransaction is the JSON-RPC representation of a transaction of any
+// type. Fields that do not apply to the transaction type are omitted by the
+// node.
+type rpcTransaction struct {
+	Hash                 string  `json:"hash"`
+	Type                 string  `json:"type"`
+	From                 string  `json:"from"`
+	To                   *string `json:"to"`
+	Value                string  `json:"value"`
+	Nonce                string  `json:"nonce"`
+	Gas                  string  `json:"gas"`
+	Input                string  `json:"input"`
+	BlockNumber          string  `json:"blockNumber"`
+	BlockHash            string  `json:"blockHash"`
+	TransactionIndex     string  `json:"transactionIndex"`
+	ChainID              string  `json:"chainId"`
+	GasPrice             string  `json:"gasPrice"`
+	MaxFeePerGas         string  `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas string  `json:"maxPriorityFeePerGas"`
+	MaxFeePerBlobGas     string  `json:"maxFeePerBlobGas"`
+	AccessList           []struct {
+		Address     string   `json:"address"`
+		StorageKeys []string `json:"storageKeys"`
+	} `json:"accessList"`
+	BlobVersionedHashes []string `json:"blobVersionedHashes"`
+	AuthorizationList   []struct {
+		ChainID string `json:"chainId"`
+		Address string `json:"address"`
+		Nonce   string `json:"nonce"`
+		YParity string `json:"yParity"`
+		R       string `json:"r"`
+		S       string `json:"s"`
+	} `json:"authorizationList"`
+}
+
+func (r rpcTransaction) toTransaction() (types.Transaction, error) {
+	tx := types.Transaction{
+		Hash:                r.Hash,
+		Input:               r.Input,
+		BlockHash:           r.BlockHash,
+		BlobVersionedHashes: r.BlobVersionedHashes,
+	}
+	var err error
+
+	// Nodes that predate EIP-2718 do not report a type
+	if r.Type != "" {
+		txType, err := parseHexUint64(r.Type)
+		if err != nil || txType > 0xff {
+			return tx, fmt.Errorf("invalid type %q", r.Type)
+		}
+		tx.Type = types.TxType(txType)
+	}
+
+	if tx.From, err = types.ParseAddress(r.From); err != nil {
+		return tx, fmt.Errorf("from: %v", err)
+	}
+	// Contract creations have no recipient
+	if r.To != nil && *r.To != "" {
+		if tx.To, err = types.ParseAddress(*r.To); err != nil {
+			return tx, fmt.Errorf("to: %v", err)
+		}
+	}
+
+	if tx.Value, err = parseHexBig(r.Value); err != nil {
+		return tx, fmt.Errorf("value: %v", err)
+	}
+	if tx.Nonce, err = parseHexUint64(r.Nonce); err != nil {
+		return tx, fmt.Errorf("nonce: %v", err)
+	}
+	if tx.Gas, err = parseHexUint64(r.Gas); err != nil {
+		return tx, fmt.Errorf("gas: %v", err)
+	}
+
+	// Pending transactions have no block yet
+	if r.BlockNumber != "" {
+		blockNumber, err := parseHexUint64(r.BlockNumber)
+		if err != nil {
+			return tx, fmt.Errorf("blockNumber: %v", err)
+		}
+		tx.BlockNumber = int64(blockNumber)
+		if tx.TransactionIndex, err = parseHexUint64(r.TransactionIndex); err != nil {
+			return tx, fmt.Errorf("transactionIndex: %v", err)
+		}
+	}
+
+	optional := []struct {
+		name  string
+		value string
+		dst   **big.Int
+	}{
+		{"chainId", r.ChainID, &tx.ChainID},
+		{"gasPrice", r.GasPrice, &tx.GasPrice},
+		{"maxFeePerGas", r.MaxFeePerGas, &tx.MaxFeePerGas},
+		{"maxPriorityFeePerGas", r.MaxPriorityFeePerGas, &tx.MaxPriorityFeePerGas},
+		{"maxFeePerBlobGas", r.MaxFeePerBlobGas, &tx.MaxFeePerBlobGas},
+	}
+	for _, field := range optional {
+		if field.value == "" {
+			continue
+		}
+		if *field.dst, err = parseHexBig(field.value); err != nil {
+			return tx, fmt.Errorf("%s: %v", field.name, err)
+		}
+	}
+
+	for _, entry := range r.AccessList {
+		address, err := types.ParseAddress(entry.Address)
+		if err != nil {
+			return tx, fmt.Errorf("accessList: %v", err)
+		}
+		tx.AccessList = append(tx.AccessList, types.AccessTuple{Address: address, StorageKeys: entry.StorageKeys})
+	}
+
+	for _, auth := range r.AuthorizationList {
+		authorization := types.Authorization{R: auth.R, S: auth.S}
+		if authorization.ChainID, err = parseHexBig(auth.ChainID); err != nil {
+			return tx, fmt.Errorf("authorizationList: chainId: %v", err)
+		}
+		if authorization.Address, err = types.ParseAddress(auth.Address); err != nil {
+			return tx, fmt.Errorf("authorizationList: %v", err)
+		}
+		if authorization.Nonce, err = parseHexUint64(auth.Nonce); err != nil {
+			return tx, fmt.Errorf("authorizationList: nonce: %v", err)
+		}
+		if authorization.YParity, err = parseHexUint64(auth.YParity); err != nil {
+			return tx, fmt.Errorf("authorizationList: yParity: %v", err)
+		}
+		tx.AuthorizationList = append(tx.AuthorizationList, authorization)
+	}
+
+	return tx, nil
+}
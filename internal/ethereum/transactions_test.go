@@ -0,0 +1,166 @@
+package ethereum
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// txFixture is a transaction as a node returns it, with the fields shared
+// by every type; each case adds the fields of its type
+const txFixture = `{
+	"hash": "0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b",
+	"from": "0xa7d9ddbe1f17865597fbd27ec712455208b6b76d",
+	"to": "0xf02c1c8e6114b1dbe8937a39260b5b0a374432bb",
+	"value": "0xf3dbb76162000",
+	"nonce": "0x15",
+	"gas": "0xc350",
+	"input": "0x68656c6c6f21",
+	"blockNumber": "0x5daf3b",
+	"blockHash": "0x1d59ff54b1eb26b013ce3cb5fc9dab3705b415a67127a003c3e61eb445bb8df2",
+	"transactionIndex": "0x41",
+	"v": "0x25",
+	"r": "0x1b5e176d927f8e9ab405058b2d2457392da3e20f328b16ddabcebc33eaac5fea",
+	"s": "0x4ba69724e8f69de52f0125ad8b3c5c2cef33019bac3249e2c0a2192766d1721c"
+	%s
+}`
+
+func TestRPCTransactionDecodesTypes(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields string
+		check  func(t *testing.T, tx types.Transaction)
+	}{
+		{
+			name:   "pre-EIP-2718",
+			fields: `, "gasPrice": "0x4a817c800"`,
+			check: func(t *testing.T, tx types.Transaction) {
+				if tx.Type != types.TxTypeLegacy || tx.GasPrice.Cmp(big.NewInt(20000000000)) != 0 {
+					t.Errorf("Unexpected type %d and gas price %v", tx.Type, tx.GasPrice)
+				}
+			},
+		},
+		{
+			name:   "legacy",
+			fields: `, "type": "0x0", "chainId": "0x1", "gasPrice": "0x4a817c800"`,
+			check: func(t *testing.T, tx types.Transaction) {
+				if tx.Type != types.TxTypeLegacy || tx.ChainID.Int64() != 1 || tx.MaxFeePerGas != nil {
+					t.Errorf("Unexpected legacy transaction: %+v", tx)
+				}
+			},
+		},
+		{
+			name: "access list",
+			fields: `, "type": "0x1", "chainId": "0x1", "gasPrice": "0x4a817c800",
+				"accessList": [{"address": "0xde0b295669a9fd93d5f28d9ec85e40f4cb697bae",
+					"storageKeys": ["0x0000000000000000000000000000000000000000000000000000000000000003"]}]`,
+			check: func(t *testing.T, tx types.Transaction) {
+				if tx.Type != types.TxTypeAccessList || len(tx.AccessList) != 1 {
+					t.Fatalf("Unexpected access list transaction: %+v", tx)
+				}
+				entry := tx.AccessList[0]
+				if entry.Address != "0xde0b295669a9fd93d5f28d9ec85e40f4cb697bae" || len(entry.StorageKeys) != 1 {
+					t.Errorf("Unexpected access list entry: %+v", entry)
+				}
+			},
+		},
+		{
+			name: "dynamic fee",
+			fields: `, "type": "0x2", "chainId": "0x1", "gasPrice": "0x3b9aca0e",
+				"maxFeePerGas": "0x77359400", "maxPriorityFeePerGas": "0x3b9aca00", "accessList": []`,
+			check: func(t *testing.T, tx types.Transaction) {
+				if tx.Type != types.TxTypeDynamicFee ||
+					tx.MaxFeePerGas.Cmp(big.NewInt(2000000000)) != 0 ||
+					tx.MaxPriorityFeePerGas.Cmp(big.NewInt(1000000000)) != 0 ||
+					len(tx.AccessList) != 0 {
+					t.Errorf("Unexpected dynamic fee transaction: %+v", tx)
+				}
+			},
+		},
+		{
+			name: "blob",
+			fields: `, "type": "0x3", "chainId": "0x1", "maxFeePerGas": "0x77359400",
+				"maxPriorityFeePerGas": "0x3b9aca00", "maxFeePerBlobGas": "0x3e8", "accessList": [],
+				"blobVersionedHashes": ["0x01a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8"]`,
+			check: func(t *testing.T, tx types.Transaction) {
+				if tx.Type != types.TxTypeBlob || tx.MaxFeePerBlobGas.Cmp(big.NewInt(1000)) != 0 {
+					t.Errorf("Unexpected blob transaction: %+v", tx)
+				}
+				if len(tx.BlobVersionedHashes) != 1 || tx.BlobVersionedHashes[0][:4] != "0x01" {
+					t.Errorf("Unexpected blob hashes: %v", tx.BlobVersionedHashes)
+				}
+			},
+		},
+		{
+			name: "set code",
+			fields: `, "type": "0x4", "chainId": "0x1", "maxFeePerGas": "0x77359400",
+				"maxPriorityFeePerGas": "0x3b9aca00", "accessList": [],
+				"authorizationList": [{"chainId": "0x1", "address": "0x63c0c19a282a1b52b07dd5a65b58948a07dae32b",
+					"nonce": "0x2", "yParity": "0x1",
+					"r": "0x5c1b6f8eb8e4d4ba2a9e3d1ef9e53a23f1e1d2d0c8a7a6f3f6d8d31a2b2c4a11",
+					"s": "0x3e2c8c0d41d44c2b0a34e1c3a2d8a2b4c7d0e5f9a8b7c6d5e4f3a2b1c0d9e8f7"}]`,
+			check: func(t *testing.T, tx types.Transaction) {
+				if tx.Type != types.TxTypeSetCode || len(tx.AuthorizationList) != 1 {
+					t.Fatalf("Unexpected set code transaction: %+v", tx)
+				}
+				auth := tx.AuthorizationList[0]
+				if auth.ChainID.Int64() != 1 || auth.Address != "0x63c0c19a282a1b52b07dd5a65b58948a07dae32b" ||
+					auth.Nonce != 2 || auth.YParity != 1 || auth.R == "" || auth.S == "" {
+					t.Errorf("Unexpected authorization: %+v", auth)
+				}
+			},
+		},
+		{
+			name:   "contract creation",
+			fields: `, "type": "0x2", "maxFeePerGas": "0x1", "maxPriorityFeePerGas": "0x1", "to": null`,
+			check: func(t *testing.T, tx types.Transaction) {
+				if tx.To != "" {
+					t.Errorf("Expected no recipient, got %s", tx.To)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var raw rpcTransaction
+			if err := json.Unmarshal([]byte(fmt.Sprintf(txFixture, tt.fields)), &raw); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			tx, err := raw.toTransaction()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			// Fields shared by every type
+			if tx.Hash != "0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b" ||
+				tx.From != "0xa7d9ddbe1f17865597fbd27ec712455208b6b76d" ||
+				tx.Value.Cmp(big.NewInt(4290000000000000)) != 0 ||
+				tx.Nonce != 21 || tx.Gas != 50000 || tx.Input != "0x68656c6c6f21" ||
+				tx.BlockNumber != 6139707 || tx.TransactionIndex != 65 {
+				t.Errorf("Unexpected common fields: %+v", tx)
+			}
+			tt.check(t, tx)
+		})
+	}
+}
+
+func TestRPCTransactionRejectsMalformedFields(t *testing.T) {
+	for _, fields := range []string{
+		`, "type": "0x100"`,
+		`, "maxFeePerBlobGas": "0xzz"`,
+		`, "accessList": [{"address": "0x123", "storageKeys": []}]`,
+		`, "authorizationList": [{"chainId": "0x1", "address": "0x63c0c19a282a1b52b07dd5a65b58948a07dae32b", "nonce": "nope", "yParity": "0x0"}]`,
+	} {
+		var raw rpcTransaction
+		if err := json.Unmarshal([]byte(fmt.Sprintf(txFixture, fields)), &raw); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := raw.toTransaction(); err == nil {
+			t.Errorf("Expected an error for %s", fields)
+		}
+	}
+}
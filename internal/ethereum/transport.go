@@ -0,0 +1,132 @@
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// requestTimeout bounds a single JSON-RPC request
+const requestTimeout = 10 * time.Second
+
+// transport delivers JSON-RPC requests to the node and returns their
+// responses
+type transport interface {
+	roundTrip(ctx context.Context, req *JSONRPCRequest) (*JSONRPCResponse, error)
+	// batchRoundTrip sends reqs as a single batch. The responses may come
+	// in any order and some may be missing.
+	batchRoundTrip(ctx context.Context, reqs []*JSONRPCRequest) ([]*JSONRPCResponse, error)
+}
+
+// newTransport selects the transport matching rpcURL: http(s) and ws(s)
+// URLs, and ipc:// URLs or absolute filesystem paths for Unix sockets
+func newTransport(rpcURL string) (transport, error) {
+	switch {
+	case strings.HasPrefix(rpcURL, "http://"), strings.HasPrefix(rpcURL, "https://"):
+		return &httpTransport{
+			url:    rpcURL,
+			client: &http.Client{Timeout: requestTimeout},
+		}, nil
+	case strings.HasPrefix(rpcURL, "ws://"), strings.HasPrefix(rpcURL, "wss://"):
+		return newStreamTransport(dialWebSocket(rpcURL)), nil
+	case strings.HasPrefix(rpcURL, "ipc://"):
+		return newStreamTransport(dialIPC(strings.TrimPrefix(rpcURL, "ipc://"))), nil
+	case filepath.IsAbs(rpcURL):
+		return newStreamTransport(dialIPC(rpcURL)), nil
+	}
+	return nil, fmt.Errorf("unsupported RPC URL scheme in %q: expected http(s), ws(s), ipc or an absolute socket path", rpcURL)
+}
+
+// httpTransport sends every request as a separate HTTP POST
+type httpTransport struct {
+	url    string
+	client *http.Client
+}
+
+func (t *httpTransport) roundTrip(ctx context.Context, payload *JSONRPCRequest) (*JSONRPCResponse, error) {
+	body, err := t.post(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse JSON-RPC response
+	var rpcResp JSONRPCResponse
+	if err := json.Unmarshal(body, &rpcResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON-RPC response: %v", err)
+	}
+	return &rpcResp, nil
+}
+
+func (t *httpTransport) batchRoundTrip(ctx context.Context, reqs []*JSONRPCRequest) ([]*JSONRPCResponse, error) {
+	body, err := t.post(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+	return parseBatchResponse(body)
+}
+
+// post sends payload to the node and returns the response body
+func (t *httpTransport) post(ctx context.Context, payload interface{}) ([]byte, error) {
+	// Convert payload to JSON
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON-RPC request: %v", err)
+	}
+
+	// Create HTTP request
+	req, err := http.NewRequestWithContext(ctx, "POST", t.url, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Send request
+	resp, err := t.client.Do(req)
+	if err != nil {
+		// Drop the URL, which often holds an API key, from the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusRequestEntityTooLarge {
+		return nil, errBatchTooLarge
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &HTTPError{StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+
+	// Read response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	// Some nodes answer JSON-RPC errors with an HTTP error status; keep
+	// those so that they are reported like any other error response
+	if resp.StatusCode >= 300 && !isJSONRPCResponse(body) {
+		return nil, &HTTPError{StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+	return body, nil
+}
+
+// isJSONRPCResponse reports whether body holds a JSON-RPC response or
+// batch of responses
+func isJSONRPCResponse(body []byte) bool {
+	if isBatchResponse(body) {
+		return true
+	}
+	var resp JSONRPCResponse
+	return json.Unmarshal(body, &resp) == nil && (resp.Error != nil || resp.Result != nil)
+}
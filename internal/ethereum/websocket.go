@@ -0,0 +1,81 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsPingInterval is how often an idle connection is probed
+	wsPingInterval = 30 * time.Second
+	// wsReadTimeout closes a connection that received nothing, not even a
+	// pong, for this long
+	wsReadTimeout = 2 * wsPingInterval
+)
+
+// wsConn is a WebSocket connection kept alive with pings
+type wsConn struct {
+	conn *websocket.Conn
+	done chan struct{}
+	once sync.Once
+}
+
+// dialWebSocket returns a dialFunc connecting to a ws(s):// endpoint
+func dialWebSocket(url string) dialFunc {
+	return func(ctx context.Context) (streamConn, error) {
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %v", redactURL(url), err)
+		}
+
+		c := &wsConn{conn: conn, done: make(chan struct{})}
+		conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+		})
+		go c.pingLoop()
+		return c, nil
+	}
+}
+
+func (c *wsConn) readMessage() ([]byte, error) {
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	c.conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+	return data, nil
+}
+
+func (c *wsConn) writeMessage(v interface{}) error {
+	c.conn.SetWriteDeadline(time.Now().Add(requestTimeout))
+	return c.conn.WriteJSON(v)
+}
+
+func (c *wsConn) close() error {
+	c.once.Do(func() { close(c.done) })
+	return c.conn.Close()
+}
+
+// pingLoop probes the connection so that a silently dropped connection is
+// detected by the read deadline
+func (c *wsConn) pingLoop() {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			// WriteControl may be called concurrently with other writes
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(requestTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
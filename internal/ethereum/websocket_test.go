@@ -0,0 +1,122 @@
+package ethereum
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// newWSNode serves eth_blockNumber and eth_subscribe over WebSocket. Every
+// subscription immediately receives one new head, after which the node drops
+// the connection if dropAfterHead is set.
+func newWSNode(t *testing.T, dropAfterHead *atomic.Bool) (*httptest.Server, *atomic.Int32) {
+	var connections atomic.Int32
+	upgrader := websocket.Upgrader{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		connections.Add(1)
+
+		for {
+			var req JSONRPCRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+
+			switch req.Method {
+			case "eth_blockNumber":
+				conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": "0x64"})
+			case "eth_subscribe":
+				conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": "0xsub"})
+				conn.WriteJSON(map[string]interface{}{
+					"jsonrpc": "2.0",
+					"method":  "eth_subscription",
+					"params": map[string]interface{}{
+						"subscription": "0xsub",
+						"result": map[string]string{
+							"number": "0x65", "hash": "0xblock101", "parentHash": "0xblock100",
+							"timestamp": "0x1", "miner": "0x0000000000000000000000000000000000000000",
+							"gasUsed": "0x0", "gasLimit": "0x1",
+						},
+					},
+				})
+				if dropAfterHead.Load() {
+					return
+				}
+			default:
+				conn.WriteJSON(map[string]interface{}{
+					"jsonrpc": "2.0", "id": req.ID,
+					"error": map[string]interface{}{"code": -32601, "message": "method not found"},
+				})
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &connections
+}
+
+func TestWebSocketSubscriptionReconnects(t *testing.T) {
+	var dropAfterHead atomic.Bool
+	dropAfterHead.Store(true)
+	server, connections := newWSNode(t, &dropAfterHead)
+
+	client, err := NewClient("ws" + strings.TrimPrefix(server.URL, "http"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	heads := make(chan types.BlockHeader, 1)
+	sub, err := client.SubscribeNewHeads(context.Background(), heads)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	select {
+	case head := <-heads:
+		if head.Number != 101 || head.Hash != "0xblock101" {
+			t.Errorf("Unexpected head: %+v", head)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for new head")
+	}
+
+	select {
+	case <-sub.Err():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected subscription error after the connection was dropped")
+	}
+
+	// The next request dials a new connection
+	dropAfterHead.Store(false)
+	number, err := client.GetBlockNumber(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if number != 100 || connections.Load() != 2 {
+		t.Errorf("Expected block 100 over a second connection, got %d over %d", number, connections.Load())
+	}
+}
+
+func TestSubscribeNewHeadsRequiresWebSocket(t *testing.T) {
+	client, err := NewClient("http://localhost:8545")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := client.SubscribeNewHeads(context.Background(), make(chan types.BlockHeader)); err != ErrSubscriptionsUnsupported {
+		t.Errorf("Expected ErrSubscriptionsUnsupported, got %v", err)
+	}
+}
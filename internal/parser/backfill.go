@@ -0,0 +1,295 @@
+package parser
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ethereum_parser/internal/ethereum"
+	"github.com/ethereum_parser/internal/types"
+)
+
+// maxBackfillFailures is the number of consecutive failed batches after which
+// a backfill job is marked as failed
+const maxBackfillFailures = 5
+
+// rateLimitPause is the least a backfill job waits after the node rate
+// limited it
+const rateLimitPause = 10 * time.Second
+
+// errNotIndexable is returned while the next block of a backfill job is
+// beyond what the confirmation policy allows indexing
+var errNotIndexable = errors.New("block not indexable yet under the confirmation policy")
+
+// CreateBackfillJob validates and persists a backfill job without running it
+func (p *EthereumParser) CreateBackfillJob(ctx context.Context, addresses []types.Address, fromBlock, toBlock int64) (*types.BackfillJob, error) {
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("at least one address is required")
+	}
+	for _, address := range addresses {
+		if address == "" {
+			return nil, fmt.Errorf("address cannot be empty")
+		}
+	}
+	if fromBlock < 0 || toBlock < fromBlock {
+		return nil, fmt.Errorf("invalid block range %d-%d", fromBlock, toBlock)
+	}
+
+	currentBlock, err := p.GetCurrentBlock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current block: %w", err)
+	}
+	if toBlock > currentBlock {
+		return nil, fmt.Errorf("block %d is beyond the current block %d", toBlock, currentBlock)
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	job := types.BackfillJob{
+		ID:        id,
+		Addresses: addresses,
+		FromBlock: fromBlock,
+		ToBlock:   toBlock,
+		NextBlock: fromBlock,
+		Status:    types.BackfillRunning,
+	}
+	if err := p.storage.SaveBackfillJob(job); err != nil {
+		return nil, fmt.Errorf("failed to save backfill job: %v", err)
+	}
+
+	return &job, nil
+}
+
+// StartBackfill creates a backfill job and runs it in the background. If the
+// parser is not running yet, the job is picked up by Start.
+func (p *EthereumParser) StartBackfill(ctx context.Context, addresses []types.Address, fromBlock, toBlock int64) (*types.BackfillJob, error) {
+	job, err := p.CreateBackfillJob(ctx, addresses, fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.running {
+		p.runBackfillAsync(job.ID)
+	}
+	return job, nil
+}
+
+func (p *EthereumParser) GetBackfillJob(ctx context.Context, id string) (*types.BackfillJob, error) {
+	return p.storage.GetBackfillJob(id)
+}
+
+// RunBackfill processes a backfill job until it completes or ctx is
+// cancelled. Progress is saved after every batch so a cancelled or crashed
+// job resumes from the first unprocessed block.
+func (p *EthereumParser) RunBackfill(ctx context.Context, id string) error {
+	job, err := p.storage.GetBackfillJob(id)
+	if err != nil {
+		return fmt.Errorf("failed to load backfill job: %v", err)
+	}
+	if job == nil {
+		return fmt.Errorf("backfill job %s not found", id)
+	}
+	if job.Status == types.BackfillCompleted {
+		return nil
+	}
+
+	addresses := make(map[types.Address]bool, len(job.Addresses))
+	for _, address := range job.Addresses {
+		addresses[address] = true
+	}
+
+	job.Status = types.BackfillRunning
+	failures := 0
+
+	for job.NextBlock <= job.ToBlock {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := p.backfillBatch(ctx, job, addresses)
+		if err == nil {
+			failures = 0
+			job.Error = ""
+		} else {
+			job.Error = err.Error()
+
+			switch {
+			case permanentFailure(err):
+				log.Printf("Backfill job %s failed at block %d: %v", job.ID, job.NextBlock, err)
+				job.Status = types.BackfillFailed
+			case errors.Is(err, ethereum.ErrRateLimited), errors.Is(err, ethereum.ErrBlockNotFound),
+				errors.Is(err, errNotIndexable):
+				// The node is busy or behind, or the blocks are not final
+				// enough yet; these do not count as failures
+				log.Printf("Backfill job %s waiting at block %d: %v", job.ID, job.NextBlock, err)
+			default:
+				failures++
+				log.Printf("Backfill job %s failed at block %d (attempt %d): %v", job.ID, job.NextBlock, failures, err)
+				if failures >= maxBackfillFailures {
+					job.Status = types.BackfillFailed
+				}
+			}
+		}
+
+		if err := p.storage.SaveBackfillJob(*job); err != nil {
+			return fmt.Errorf("failed to save backfill job: %v", err)
+		}
+
+		if job.Status == types.BackfillFailed {
+			return fmt.Errorf("backfill job %s failed: %s", job.ID, job.Error)
+		}
+
+		if err != nil {
+			delay := p.config.PollInterval
+			if errors.Is(err, ethereum.ErrRateLimited) {
+				delay = max(delay, rateLimitPause)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+	}
+
+	job.Status = types.BackfillCompleted
+	if err := p.storage.SaveBackfillJob(*job); err != nil {
+		return fmt.Errorf("failed to save backfill job: %v", err)
+	}
+
+	log.Printf("Backfill job %s completed (blocks %d-%d)", job.ID, job.FromBlock, job.ToBlock)
+	return nil
+}
+
+// backfillBatch indexes up to CatchupBatchSize blocks starting at
+// job.NextBlock and advances job.NextBlock past every block it completed.
+// Like the live indexer, it stops at the last block the confirmation policy
+// allows indexing. Already indexed transactions are skipped by the storage.
+func (p *EthereumParser) backfillBatch(parent context.Context, job *types.BackfillJob, addresses map[types.Address]bool) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(parent), p.config.PollTimeout)
+	defer cancel()
+
+	currentBlock, err := p.GetCurrentBlock(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current block: %w", err)
+	}
+
+	state, err := p.loadConfirmationState(ctx, currentBlock)
+	if err != nil {
+		return err
+	}
+
+	first := job.NextBlock
+	if first > state.indexThrough {
+		return fmt.Errorf("block %d: %w", first, errNotIndexable)
+	}
+	last := min(first+int64(max(p.config.CatchupBatchSize, 1))-1, job.ToBlock, state.indexThrough)
+
+	fetchCtx, cancelFetch := context.WithCancel(ctx)
+	defer cancelFetch()
+	results := p.fetchBlocks(fetchCtx, first, last)
+
+	for blockNumber := first; blockNumber <= last; blockNumber++ {
+		// Stop between blocks so the current block is always completed
+		if parent.Err() != nil {
+			return nil
+		}
+
+		result := <-results[blockNumber-first]
+		if result.err != nil {
+			return fmt.Errorf("failed to get block %d: %w", blockNumber, result.err)
+		}
+
+		matches := matchAddresses(result.block.Transactions, addresses)
+		if err := p.verifyBlock(ctx, result.block, matches); err != nil {
+			return err
+		}
+		if err := p.applyReceipts(ctx, blockNumber, matches); err != nil {
+			return err
+		}
+
+		for address, txs := range matches {
+			for _, tx := range txs {
+				tx.Confirmation = state.statusOf(blockNumber)
+				if _, err := p.storage.StoreTransaction(address, tx); err != nil {
+					return fmt.Errorf("failed to store transaction %s for %s: %v", tx.Hash, address, err)
+				}
+			}
+		}
+
+		transfers, err := p.fetchTokenTransfers(ctx, result.block.BlockHeader, addresses)
+		if err != nil {
+			return err
+		}
+		if err := p.storeTokenTransfers(transfers, state.statusOf(blockNumber), false); err != nil {
+			return err
+		}
+
+		internal, err := p.fetchInternalTransfers(ctx, result.block, addresses)
+		if err != nil {
+			return err
+		}
+		if err := p.storeInternalTransfers(internal, state.statusOf(blockNumber), false); err != nil {
+			return err
+		}
+
+		job.NextBlock = blockNumber + 1
+	}
+
+	return nil
+}
+
+// resumeBackfills restarts every backfill job that was running when the
+// parser last stopped. The caller must hold p.mu.
+func (p *EthereumParser) resumeBackfills() error {
+	jobs, err := p.storage.GetBackfillJobs()
+	if err != nil {
+		return fmt.Errorf("failed to load backfill jobs: %v", err)
+	}
+
+	for _, job := range jobs {
+		if job.Status != types.BackfillRunning {
+			continue
+		}
+		log.Printf("Resuming backfill job %s at block %d", job.ID, job.NextBlock)
+		p.runBackfillAsync(job.ID)
+	}
+	return nil
+}
+
+// runBackfillAsync runs a job in the background until it completes or the
+// parser is stopped. The caller must hold p.mu.
+func (p *EthereumParser) runBackfillAsync(id string) {
+	ctx := p.backfillCtx
+	p.backfills.Add(1)
+	go func() {
+		defer p.backfills.Done()
+		if err := p.RunBackfill(ctx, id); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("Backfill job %s stopped: %v", id, err)
+		}
+	}()
+}
+
+// permanentFailure reports whether err comes from a call the node will
+// never answer, so retrying the batch is pointless
+func permanentFailure(err error) bool {
+	return errors.Is(err, ethereum.ErrMethodNotFound) || errors.Is(err, ethereum.ErrInvalidParams)
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
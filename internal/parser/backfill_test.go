@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// fetchLog records the blocks requested from the node
+type fetchLog struct {
+	mu     sync.Mutex
+	blocks map[int64]int
+}
+
+func (l *fetchLog) record(n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.blocks[n]++
+}
+
+func (l *fetchLog) count(n int64) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.blocks[n]
+}
+
+func TestRunBackfillResumesAfterRestart(t *testing.T) {
+	fetched := &fetchLog{blocks: make(map[int64]int)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := newChainParser(t, 1, func(n int64) time.Duration {
+		fetched.record(n)
+		// Interrupt the first run in the middle of the range
+		if n == 15 {
+			cancel()
+		}
+		return 0
+	})
+	p.config.CatchupBatchSize = 2
+
+	job, err := p.CreateBackfillJob(context.Background(), []types.Address{testAddress(1)}, 10, 20)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := p.RunBackfill(ctx, job.ID); err == nil {
+		t.Fatalf("Expected the interrupted run to fail")
+	}
+
+	saved, _ := p.storage.GetBackfillJob(job.ID)
+	if saved.Status != types.BackfillRunning || saved.NextBlock <= 10 || saved.NextBlock > 16 {
+		t.Fatalf("Expected a running job with saved progress, got %+v", saved)
+	}
+	resumeAt := saved.NextBlock
+
+	// A new parser on the same storage picks up where the first one stopped
+	restarted, err := NewEthereumParser(p.storage, p.config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := restarted.RunBackfill(context.Background(), job.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	saved, _ = p.storage.GetBackfillJob(job.ID)
+	if saved.Status != types.BackfillCompleted || saved.NextBlock != 21 || saved.Error != "" {
+		t.Errorf("Expected a completed job, got %+v", saved)
+	}
+	for n := int64(10); n < resumeAt; n++ {
+		if fetched.count(n) != 1 {
+			t.Errorf("Expected block %d before the resume point to be fetched once, got %d", n, fetched.count(n))
+		}
+	}
+	for n := resumeAt; n <= 20; n++ {
+		if fetched.count(n) == 0 {
+			t.Errorf("Expected block %d to be fetched after the restart", n)
+		}
+	}
+}
+
+func TestRunBackfillStopsAtConfirmationPolicy(t *testing.T) {
+	fetched := &fetchLog{blocks: make(map[int64]int)}
+	p := newChainParser(t, 1, func(n int64) time.Duration {
+		fetched.record(n)
+		return 0
+	})
+	p.config.ConfirmationPolicy = PolicyFinalized
+	p.config.PollInterval = 10 * time.Millisecond
+
+	// Blocks above the finalized block 90 wait like they do for the live indexer
+	job, err := p.CreateBackfillJob(context.Background(), []types.Address{testAddress(1)}, 85, 95)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := p.RunBackfill(ctx, job.ID); err == nil {
+		t.Fatalf("Expected the run to wait until cancelled")
+	}
+
+	saved, _ := p.storage.GetBackfillJob(job.ID)
+	if saved.Status != types.BackfillRunning || saved.NextBlock != 91 {
+		t.Errorf("Expected a running job waiting at block 91, got %+v", saved)
+	}
+	for n := int64(91); n <= 95; n++ {
+		if fetched.count(n) != 0 {
+			t.Errorf("Expected block %d not to be fetched", n)
+		}
+	}
+}
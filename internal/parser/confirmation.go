@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// Confirmation policies
+const (
+	// PolicyLatest indexes blocks as soon as they are mined
+	PolicyLatest = "latest"
+	// PolicyConfirmations indexes blocks once they have N confirmations
+	PolicyConfirmations = "confirmations"
+	// PolicySafe indexes blocks up to the "safe" block tag
+	PolicySafe = "safe"
+	// PolicyFinalized indexes blocks up to the "finalized" block tag
+	PolicyFinalized = "finalized"
+)
+
+func isValidPolicy(policy string) bool {
+	switch policy {
+	case PolicyLatest, PolicyConfirmations, PolicySafe, PolicyFinalized:
+		return true
+	}
+	return false
+}
+
+// confirmationState is the view of chain finality for a single poll cycle
+type confirmationState struct {
+	// indexThrough is the highest block the policy allows indexing
+	indexThrough int64
+	// confirmedThrough is the highest block considered confirmed
+	confirmedThrough int64
+	// finalizedThrough is the highest finalized block, or -1 if unknown
+	finalizedThrough int64
+}
+
+// statusOf returns the confirmation status of a transaction in blockNumber
+func (s confirmationState) statusOf(blockNumber int64) types.ConfirmationStatus {
+	switch {
+	case blockNumber <= s.finalizedThrough:
+		return types.StatusFinalized
+	case blockNumber <= s.confirmedThrough:
+		return types.StatusConfirmed
+	default:
+		return types.StatusPendingConfirmation
+	}
+}
+
+// loadConfirmationState resolves the configured policy against the current head
+func (p *EthereumParser) loadConfirmationState(ctx context.Context, head int64) (confirmationState, error) {
+	depth := int64(p.config.Confirmations)
+	state := confirmationState{
+		indexThrough:     head,
+		confirmedThrough: head - depth,
+		finalizedThrough: -1,
+	}
+
+	finalized, err := p.client.GetBlockHeaderByTag(ctx, "finalized")
+	if err != nil {
+		// Only fatal when the policy depends on it; some chains have no finality
+		if p.config.ConfirmationPolicy == PolicyFinalized {
+			return state, fmt.Errorf("failed to get finalized block: %w", err)
+		}
+		log.Printf("Failed to get finalized block: %v", err)
+	} else {
+		state.finalizedThrough = finalized.Number
+	}
+
+	switch p.config.ConfirmationPolicy {
+	case PolicyConfirmations:
+		state.indexThrough = head - depth
+	case PolicySafe:
+		safe, err := p.client.GetBlockHeaderByTag(ctx, "safe")
+		if err != nil {
+			return state, fmt.Errorf("failed to get safe block: %w", err)
+		}
+		state.indexThrough = safe.Number
+		state.confirmedThrough = max(state.confirmedThrough, safe.Number)
+	case PolicyFinalized:
+		state.indexThrough = state.finalizedThrough
+		state.confirmedThrough = state.finalizedThrough
+	}
+
+	return state, nil
+}
+
+// updateConfirmations raises the confirmation watermarks to state. Stored
+// transactions are read with the status the watermarks of the last
+// checkpoint imply, so nothing is rewritten; the watermarks are saved with
+// the next checkpoint, or right away when no block is due this cycle.
+func (p *EthereumParser) updateConfirmations(state confirmationState) error {
+	moved := state.confirmedThrough > p.confirmation.confirmedThrough ||
+		state.finalizedThrough > p.confirmation.finalizedThrough
+
+	p.confirmation.indexThrough = state.indexThrough
+	p.confirmation.confirmedThrough = max(p.confirmation.confirmedThrough, state.confirmedThrough)
+	p.confirmation.finalizedThrough = max(p.confirmation.finalizedThrough, state.finalizedThrough)
+
+	if !moved || p.checkpoint == nil || state.indexThrough > p.checkpoint.BlockNumber {
+		return nil
+	}
+
+	checkpoint := p.checkpointAt(types.BlockHeader{Number: p.checkpoint.BlockNumber, Hash: p.checkpoint.BlockHash})
+	if err := p.storage.SaveCheckpoint(checkpoint); err != nil {
+		return err
+	}
+	p.checkpoint = &checkpoint
+	return nil
+}
+
+// checkpointAt returns the checkpoint for header with the current
+// confirmation watermarks
+func (p *EthereumParser) checkpointAt(header types.BlockHeader) types.Checkpoint {
+	return types.Checkpoint{
+		BlockNumber:      header.Number,
+		BlockHash:        header.Hash,
+		ConfirmedThrough: p.confirmation.confirmedThrough,
+		FinalizedThrough: p.confirmation.finalizedThrough,
+	}
+}
@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+func TestLoadConfirmationState(t *testing.T) {
+	p := newChainParser(t, 1, func(int64) time.Duration { return 0 })
+	p.config.Confirmations = 3
+
+	tests := []struct {
+		policy string
+		want   confirmationState
+	}{
+		{PolicyLatest, confirmationState{indexThrough: 100, confirmedThrough: 97, finalizedThrough: 90}},
+		{PolicyConfirmations, confirmationState{indexThrough: 97, confirmedThrough: 97, finalizedThrough: 90}},
+		{PolicySafe, confirmationState{indexThrough: 95, confirmedThrough: 97, finalizedThrough: 90}},
+		{PolicyFinalized, confirmationState{indexThrough: 90, confirmedThrough: 90, finalizedThrough: 90}},
+	}
+
+	for _, tt := range tests {
+		p.config.ConfirmationPolicy = tt.policy
+		state, err := p.loadConfirmationState(context.Background(), 100)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if state != tt.want {
+			t.Errorf("Policy %s: expected %+v, got %+v", tt.policy, tt.want, state)
+		}
+	}
+}
+
+func TestConfirmationStatusOf(t *testing.T) {
+	state := confirmationState{indexThrough: 100, confirmedThrough: 97, finalizedThrough: 90}
+
+	tests := []struct {
+		block int64
+		want  types.ConfirmationStatus
+	}{
+		{85, types.StatusFinalized},
+		{90, types.StatusFinalized},
+		{91, types.StatusConfirmed},
+		{97, types.StatusConfirmed},
+		{98, types.StatusPendingConfirmation},
+		{100, types.StatusPendingConfirmation},
+	}
+
+	for _, tt := range tests {
+		if got := state.statusOf(tt.block); got != tt.want {
+			t.Errorf("Block %d: expected %s, got %s", tt.block, tt.want, got)
+		}
+	}
+}
+
+func TestUpdateConfirmations(t *testing.T) {
+	p := newChainParser(t, 1, func(int64) time.Duration { return 0 })
+	p.window = newBlockWindow(p.config.ReorgWindow)
+	p.confirmation = confirmationState{confirmedThrough: -1, finalizedThrough: -1}
+
+	address := testAddress(1)
+	for _, block := range []int64{90, 95, 99} {
+		tx := types.Transaction{Hash: fmt.Sprintf("0x%x", block), BlockNumber: block, Confirmation: types.StatusPendingConfirmation}
+		if _, err := p.storage.StoreTransaction(address, tx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := p.advanceCheckpoint(types.BlockHeader{Number: 99, Hash: "0xblock99"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Caught up, so the watermarks are saved right away
+	state := confirmationState{indexThrough: 99, confirmedThrough: 97, finalizedThrough: 90}
+	if err := p.updateConfirmations(state); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	txs, _ := p.storage.GetTransactions(address)
+	want := []types.ConfirmationStatus{types.StatusFinalized, types.StatusConfirmed, types.StatusPendingConfirmation}
+	for i, tx := range txs {
+		if tx.Confirmation != want[i] {
+			t.Errorf("Block %d: expected %s, got %s", tx.BlockNumber, want[i], tx.Confirmation)
+		}
+	}
+
+	// A node reporting an older finalized block does not lower the watermarks
+	if err := p.updateConfirmations(confirmationState{indexThrough: 99, confirmedThrough: 96, finalizedThrough: 85}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	checkpoint, _ := p.storage.GetCheckpoint()
+	if checkpoint.ConfirmedThrough != 97 || checkpoint.FinalizedThrough != 90 {
+		t.Errorf("Expected watermarks 97 and 90, got %+v", checkpoint)
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/ethereum_parser/internal/config"
+	"github.com/ethereum_parser/internal/decoder"
 	"github.com/ethereum_parser/internal/ethereum"
 	"github.com/ethereum_parser/internal/storage"
 	"github.com/ethereum_parser/internal/types"
@@ -19,12 +21,77 @@ import (
 type EthereumParser struct {
 	client      *ethereum.Client
 	storage     storage.Storage
-	subscribers map[string]bool
+	abis        *decoder.Registry
+	subscribers map[types.Address]bool
 	config      *config.Config
+
+	mu      sync.RWMutex
+	stop    chan struct{}
+	done    chan struct{}
+	running bool
+
+	backfillCtx    context.Context
+	cancelBackfill context.CancelFunc
+	backfills      sync.WaitGroup
+
+	// checkpoint, window and confirmation are only accessed by the polling
+	// goroutine
+	checkpoint   *types.Checkpoint
+	window       *blockWindow
+	confirmation confirmationState
+
+	// pending tracks the unresolved mempool transactions by hash and
+	// pendingNonces their hashes by sender and nonce
+	pendingMu     sync.Mutex
+	pending       map[string]*pendingEntry
+	pendingNonces map[string]string
+}
+
+// Webhook notification events
+const (
+	eventNewTransaction      = "new_transaction"
+	eventNewTokenTransfer    = "new_token_transfer"
+	eventNewInternalTransfer = "new_internal_transfer"
+	eventReorged             = "reorged"
+
+	eventNewPendingTransaction     = "new_pending_transaction"
+	eventPendingTransactionUpdated = "pending_transaction_updated"
+)
+
+var eventMessages = map[string]string{
+	eventNewTransaction:      "New transaction detected",
+	eventNewTokenTransfer:    "New token transfer detected",
+	eventNewInternalTransfer: "New internal transfer detected",
+	eventReorged:             "Removed by chain reorganization",
+
+	eventNewPendingTransaction:     "New pending transaction detected",
+	eventPendingTransactionUpdated: "Pending transaction resolved",
 }
 
 func NewEthereumParser(storage storage.Storage, cfg *config.Config) (*EthereumParser, error) {
-	client, err := ethereum.NewClient(cfg.EthereumRPCURL)
+	if !isValidPolicy(cfg.ConfirmationPolicy) {
+		return nil, fmt.Errorf("unknown confirmation policy %q", cfg.ConfirmationPolicy)
+	}
+	if !isValidTraceMode(cfg.TraceMode) {
+		return nil, fmt.Errorf("unknown trace mode %q", cfg.TraceMode)
+	}
+
+	var endpoints []ethereum.Endpoint
+	for _, endpoint := range cfg.Endpoints() {
+		endpoints = append(endpoints, ethereum.Endpoint{URL: endpoint.URL, Weight: endpoint.Weight})
+	}
+	client, err := ethereum.NewPoolClient(endpoints, ethereum.PoolConfig{
+		MaxHeadLag: int64(cfg.MaxHeadLag),
+		Probation:  cfg.ProviderProbation,
+		Quorum:     cfg.Quorum,
+		RateLimit:  cfg.RPCRateLimit,
+		RateBurst:  cfg.RPCRateBurst,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	abis, err := decoder.NewRegistry(cfg.ABIDir)
 	if err != nil {
 		return nil, err
 	}
@@ -32,16 +99,23 @@ func NewEthereumParser(storage storage.Storage, cfg *config.Config) (*EthereumPa
 	return &EthereumParser{
 		client:      client,
 		storage:     storage,
-		subscribers: make(map[string]bool),
+		abis:        abis,
+		subscribers: make(map[types.Address]bool),
 		config:      cfg,
+
+		pending:       make(map[string]*pendingEntry),
+		pendingNonces: make(map[string]string),
 	}, nil
 }
 
-func (p *EthereumParser) GetCurrentBlock() (int64, error) {
-	return p.client.GetBlockNumber()
+func (p *EthereumParser) GetCurrentBlock(ctx context.Context) (int64, error) {
+	return p.client.GetBlockNumber(ctx)
 }
 
-func (p *EthereumParser) Subscribe(address string) bool {
+func (p *EthereumParser) Subscribe(ctx context.Context, address types.Address) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if _, exists := p.subscribers[address]; exists {
 		return false
 	}
@@ -49,87 +123,245 @@ func (p *EthereumParser) Subscribe(address string) bool {
 	return true
 }
 
-func (p *EthereumParser) GetTransactions(address string) ([]types.Transaction, error) {
-	return p.storage.GetTransactions(address)
+// GetTransactions returns the stored transactions of an address with their
+// input decoded using the ABI registry
+func (p *EthereumParser) GetTransactions(ctx context.Context, address types.Address) ([]types.Transaction, error) {
+	txs, err := p.storage.GetTransactions(address)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode into a copy, the storage owns the returned slice
+	decoded := make([]types.Transaction, len(txs))
+	for i, tx := range txs {
+		if tx.To != "" {
+			tx.Decoded = p.abis.Decode(tx.To, tx.Input)
+		}
+		decoded[i] = tx
+	}
+	return decoded, nil
+}
+
+func (p *EthereumParser) GetPendingTransactions(ctx context.Context, address types.Address) ([]types.PendingTransaction, error) {
+	return p.storage.GetPendingTransactions(address)
+}
+
+func (p *EthereumParser) GetTokenTransfers(ctx context.Context, address types.Address) ([]types.TokenTransfer, error) {
+	return p.storage.GetTokenTransfers(address)
 }
 
-func (p *EthereumParser) startBlockPolling() {
-	ticker := time.NewTicker(15 * time.Second)
-	defer ticker.Stop()
+func (p *EthereumParser) GetInternalTransfers(ctx context.Context, address types.Address) ([]types.InternalTransfer, error) {
+	return p.storage.GetInternalTransfers(address)
+}
 
-	var lastProcessedBlock int64
+// matchTransactions returns the transactions involving subscribed addresses,
+// keyed by subscriber
+func (p *EthereumParser) matchTransactions(txs []types.Transaction) map[types.Address][]types.Transaction {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 
-	for range ticker.C {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	return matchAddresses(txs, p.subscribers)
+}
 
-		currentBlock, err := p.GetCurrentBlock()
-		if err != nil {
-			log.Printf("Failed to get current block: %v", err)
-			cancel()
-			continue
-		}
+// subscriberSet returns a copy of the subscribed addresses
+func (p *EthereumParser) subscriberSet() map[types.Address]bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 
-		// Ensure sequential block processing
-		if lastProcessedBlock == 0 {
-			lastProcessedBlock = currentBlock
+	set := make(map[types.Address]bool, len(p.subscribers))
+	for address := range p.subscribers {
+		set[address] = true
+	}
+	return set
+}
+
+// matchAddresses returns the transactions sent from or to an address in the
+// set, keyed by address. Each transaction is looked up in the set, so the
+// cost grows with the number of transactions and not with the set size.
+func matchAddresses(txs []types.Transaction, addresses map[types.Address]bool) map[types.Address][]types.Transaction {
+	matches := make(map[types.Address][]types.Transaction)
+	for _, tx := range txs {
+		if addresses[tx.From] {
+			matches[tx.From] = append(matches[tx.From], tx)
+		}
+		if tx.To != "" && tx.To != tx.From && addresses[tx.To] {
+			matches[tx.To] = append(matches[tx.To], tx)
 		}
+	}
+	return matches
+}
+
+// processBlock indexes the transactions of a block for every subscribed
+// address. An error means the block was not fully processed.
+func (p *EthereumParser) processBlock(ctx context.Context, block *types.Block, status types.ConfirmationStatus) error {
+	matches := p.matchTransactions(block.Transactions)
+	if err := p.verifyBlock(ctx, block, matches); err != nil {
+		return err
+	}
+
+	if err := p.reconcilePending(block); err != nil {
+		return err
+	}
 
-		for blockNumber := lastProcessedBlock + 1; blockNumber <= currentBlock; blockNumber++ {
-			p.processBlock(ctx, blockNumber)
+	if err := p.applyReceipts(ctx, block.Number, matches); err != nil {
+		return err
+	}
+
+	for address, txs := range matches {
+		for _, tx := range txs {
+			tx.Confirmation = status
+			stored, err := p.storage.StoreTransaction(address, tx)
+			if err != nil {
+				return fmt.Errorf("failed to store transaction %s for %s: %v", tx.Hash, address, err)
+			}
+
+			// A block retried after a later step failed is not notified twice
+			if stored {
+				p.notifyTransaction(tx, address, eventNewTransaction, p.config.WebhookURL)
+			}
 		}
+	}
+
+	subscribers := p.subscriberSet()
+	transfers, err := p.fetchTokenTransfers(ctx, block.BlockHeader, subscribers)
+	if err != nil {
+		return err
+	}
+	if err := p.storeTokenTransfers(transfers, status, true); err != nil {
+		return err
+	}
 
-		lastProcessedBlock = currentBlock
-		cancel()
+	internal, err := p.fetchInternalTransfers(ctx, block, subscribers)
+	if err != nil {
+		return err
 	}
+	return p.storeInternalTransfers(internal, status, true)
 }
 
-func (p *EthereumParser) processBlock(ctx context.Context, blockNumber int64) {
-	for address := range p.subscribers {
-		var txs []types.Transaction
-		var err error
-
-		// Retry logic
-		for retries := 0; retries < 3; retries++ {
-			txs, err = p.client.GetTransactionsForAddress(ctx, address, blockNumber)
-			if err == nil {
-				break
+// verifyBlock checks the block and its matched transactions against a
+// quorum of RPC providers when quorum mode is enabled. Disagreements are
+// recorded for investigation and keep the block from being indexed.
+func (p *EthereumParser) verifyBlock(ctx context.Context, block *types.Block, matches map[types.Address][]types.Transaction) error {
+	var hashes []string
+	seen := make(map[string]bool)
+	for _, txs := range matches {
+		for _, tx := range txs {
+			if !seen[tx.Hash] {
+				seen[tx.Hash] = true
+				hashes = append(hashes, tx.Hash)
 			}
-			log.Printf("Retry %d: Failed to get transactions for %s: %v", retries+1, address, err)
 		}
+	}
 
-		if err != nil {
-			log.Printf("Failed to get transactions for %s after retries: %v", address, err)
-			continue
+	disagreements, err := p.client.VerifyBlock(ctx, block, hashes)
+	for _, d := range disagreements {
+		log.Printf("RPC providers disagree on %s of block %d: expected %s, got %v", d.Subject, d.BlockNumber, d.Expected, d.Answers)
+		if err := p.storage.SaveDisagreement(d); err != nil {
+			return fmt.Errorf("failed to store disagreement on block %d: %v", d.BlockNumber, err)
 		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify block %d: %w", block.Number, err)
+	}
+	return nil
+}
 
+// GetDisagreements returns the recorded disagreements between RPC
+// providers
+func (p *EthereumParser) GetDisagreements(ctx context.Context) ([]types.Disagreement, error) {
+	return p.storage.GetDisagreements()
+}
+
+// applyReceipts fetches the receipts of the matched transactions and records
+// their execution results on them
+func (p *EthereumParser) applyReceipts(ctx context.Context, blockNumber int64, matches map[types.Address][]types.Transaction) error {
+	var hashes []string
+	seen := make(map[string]bool)
+	for _, txs := range matches {
 		for _, tx := range txs {
-			p.storage.StoreTransaction(address, tx)
+			if !seen[tx.Hash] {
+				seen[tx.Hash] = true
+				hashes = append(hashes, tx.Hash)
+			}
+		}
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
 
-			p.notifyTransaction(tx, address, p.config.WebhookURL)
+	receipts, err := p.client.GetReceipts(ctx, blockNumber, hashes)
+	if err != nil {
+		return fmt.Errorf("failed to get receipts for block %d: %w", blockNumber, err)
+	}
+
+	for _, txs := range matches {
+		for i := range txs {
+			receipt, ok := receipts[txs[i].Hash]
+			if !ok {
+				return fmt.Errorf("missing receipt for transaction %s", txs[i].Hash)
+			}
+			txs[i].ApplyReceipt(receipt)
 		}
 	}
+	return nil
 }
 
-func (p *EthereumParser) notifyTransaction(tx types.Transaction, address string, webhookURL string) {
-	payload, err := json.Marshal(map[string]interface{}{
+func (p *EthereumParser) notifyTransaction(tx types.Transaction, address types.Address, event, webhookURL string) {
+	sendNotification(webhookURL, tx.Hash, map[string]interface{}{
 		"address":      address,
 		"transaction":  tx,
-		"notification": "New transaction detected",
+		"event":        event,
+		"notification": eventMessages[event],
 	})
+}
+
+func (p *EthereumParser) notifyTokenTransfer(transfer types.TokenTransfer, address types.Address, event, webhookURL string) {
+	sendNotification(webhookURL, transfer.TransactionHash, map[string]interface{}{
+		"address":       address,
+		"tokenTransfer": transfer,
+		"event":         event,
+		"notification":  eventMessages[event],
+	})
+}
+
+func (p *EthereumParser) notifyInternalTransfer(transfer types.InternalTransfer, address types.Address, event, webhookURL string) {
+	sendNotification(webhookURL, transfer.TransactionHash, map[string]interface{}{
+		"address":          address,
+		"internalTransfer": transfer,
+		"event":            event,
+		"notification":     eventMessages[event],
+	})
+}
+
+func (p *EthereumParser) notifyPendingTransaction(tx types.PendingTransaction, address types.Address, event, webhookURL string) {
+	sendNotification(webhookURL, tx.Transaction.Hash, map[string]interface{}{
+		"address":            address,
+		"pendingTransaction": tx,
+		"event":              event,
+		"notification":       eventMessages[event],
+	})
+}
+
+func sendNotification(webhookURL, txHash string, notification map[string]interface{}) {
+	payload, err := json.Marshal(notification)
 	if err != nil {
 		log.Printf("Failed to marshal notification payload: %v", err)
 		return
 	}
 
 	if err := sendToWebhook(webhookURL, payload); err != nil {
-		log.Printf("Failed to send notification for transaction %s: %v", tx.Hash, err)
+		log.Printf("Failed to send notification for transaction %s: %v", txHash, err)
 		return
 	}
-	log.Printf("Notification sent for transaction: %s", tx.Hash)
+	log.Printf("Notification sent for transaction: %s", txHash)
 }
 
+// webhookClient sends the notifications; a stalled webhook must not hold up
+// indexing for long
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
 func sendToWebhook(url string, payload []byte) error {
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(payload))
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewBuffer(payload))
 	if err != nil {
 		return err
 	}
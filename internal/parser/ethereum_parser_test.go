@@ -0,0 +1,474 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum_parser/internal/config"
+	"github.com/ethereum_parser/internal/storage"
+	"github.com/ethereum_parser/internal/types"
+)
+
+// fakeNode is a minimal JSON-RPC node serving a single block
+type fakeNode struct {
+	server   *httptest.Server
+	block    map[string]interface{}
+	receipts []map[string]string
+	traces   []map[string]interface{}
+	// mempool holds the pending transactions served by hash
+	mempool map[string]map[string]string
+	// filtered holds the mempool hashes the filter has returned
+	filtered map[string]bool
+	// mempoolDown fails the lookups of mempool transactions
+	mempoolDown bool
+	// delay holds back every answer, or until the request is cancelled
+	delay time.Duration
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func newFakeNode(t testing.TB, txs []map[string]string) *fakeNode {
+	var receipts []map[string]string
+	for i, tx := range txs {
+		receipts = append(receipts, map[string]string{
+			"transactionHash":   tx["hash"],
+			"status":            "0x1",
+			"gasUsed":           "0x5208",
+			"cumulativeGasUsed": fmt.Sprintf("0x%x", 21000*(i+1)),
+			"effectiveGasPrice": "0x3b9aca00",
+		})
+	}
+
+	node := &fakeNode{
+		receipts: receipts,
+		filtered: make(map[string]bool),
+		calls:    make(map[string]int),
+		block: map[string]interface{}{
+			"number":        "0x64",
+			"hash":          "0xblock100",
+			"parentHash":    "0xblock99",
+			"timestamp":     "0x64f0a000",
+			"baseFeePerGas": "0x3b9aca00",
+			"miner":         testAddress(999).String(),
+			"gasUsed":       "0x5208",
+			"gasLimit":      "0x1c9c380",
+			"transactions":  txs,
+		},
+	}
+
+	node.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/webhook" {
+			node.mu.Lock()
+			node.calls["webhook"]++
+			node.mu.Unlock()
+			return
+		}
+
+		var body json.RawMessage
+		json.NewDecoder(r.Body).Decode(&body)
+
+		node.mu.Lock()
+		delay := node.delay
+		node.mu.Unlock()
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+
+		// Answer batch requests element by element
+		if strings.HasPrefix(string(body), "[") {
+			var reqs []fakeRequest
+			json.Unmarshal(body, &reqs)
+			resps := make([]interface{}, len(reqs))
+			for i, req := range reqs {
+				resps[i] = node.answer(req)
+			}
+			json.NewEncoder(w).Encode(resps)
+			return
+		}
+
+		var req fakeRequest
+		json.Unmarshal(body, &req)
+		json.NewEncoder(w).Encode(node.answer(req))
+	}))
+	t.Cleanup(node.server.Close)
+
+	return node
+}
+
+type fakeRequest struct {
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+	ID     int           `json:"id"`
+}
+
+// answer builds the JSON-RPC response to req
+func (n *fakeNode) answer(req fakeRequest) map[string]interface{} {
+	n.mu.Lock()
+	n.calls[req.Method]++
+	n.mu.Unlock()
+
+	var result interface{}
+	switch req.Method {
+	case "eth_blockNumber":
+		result = "0x64"
+	case "eth_getBlockByNumber":
+		result = n.block
+	case "eth_getBlockReceipts":
+		result = n.receipts
+	case "debug_traceBlockByNumber":
+		result = n.traces
+	case "eth_newPendingTransactionFilter":
+		result = "0x1"
+	case "eth_getFilterChanges":
+		hashes := []string{}
+		for hash := range n.mempool {
+			if !n.filtered[hash] {
+				n.filtered[hash] = true
+				hashes = append(hashes, hash)
+			}
+		}
+		result = hashes
+	case "eth_getTransactionByHash":
+		if n.mempoolDown {
+			return map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      req.ID,
+				"error":   map[string]interface{}{"code": -32000, "message": "transaction lookup failed"},
+			}
+		}
+		if tx, ok := n.mempool[req.Params[0].(string)]; ok {
+			result = tx
+		}
+	}
+
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      req.ID,
+		"result":  result,
+	}
+}
+
+func (n *fakeNode) callCount(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls[method]
+}
+
+func testAddress(i int) types.Address {
+	return types.Address(fmt.Sprintf("0x%040x", i))
+}
+
+// newTestParser creates a parser subscribed to the given number of addresses
+// and a block with one transaction from every tenth subscriber to the next
+// one, plus transactions between unrelated addresses.
+func newTestParser(t testing.TB, subscribers int) (*EthereumParser, *fakeNode, storage.Storage) {
+	var txs []map[string]string
+	for i := 0; i < 200; i++ {
+		from, to := testAddress(100000+i), testAddress(200000+i)
+		if i%10 == 0 && i+1 < subscribers {
+			from, to = testAddress(i), testAddress(i+1)
+		}
+		txs = append(txs, map[string]string{
+			"hash":                 fmt.Sprintf("0x%064x", i),
+			"type":                 "0x2",
+			"from":                 from.String(),
+			"to":                   to.String(),
+			"value":                "0x3e8",
+			"nonce":                "0x0",
+			"gas":                  "0x5208",
+			"maxFeePerGas":         "0x77359400",
+			"maxPriorityFeePerGas": "0x3b9aca00",
+			"input":                "0x",
+			"blockNumber":          "0x64",
+			"transactionIndex":     fmt.Sprintf("0x%x", i),
+		})
+	}
+
+	node := newFakeNode(t, txs)
+
+	cfg := config.NewConfig()
+	cfg.EthereumRPCURL = node.server.URL
+	cfg.WebhookURL = node.server.URL + "/webhook"
+
+	store := storage.NewMemoryStorage()
+	p, err := NewEthereumParser(store, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i := 0; i < subscribers; i++ {
+		p.Subscribe(context.Background(), testAddress(i))
+	}
+
+	return p, node, store
+}
+
+func TestProcessBlockFetchesBlockOnce(t *testing.T) {
+	p, node, store := newTestParser(t, 500)
+
+	block, err := p.fetchBlock(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := p.processBlock(context.Background(), block, types.StatusConfirmed); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if calls := node.callCount("eth_getBlockByNumber"); calls != 1 {
+		t.Errorf("Expected 1 block fetch, got %d", calls)
+	}
+
+	// Both sender and recipient are subscribed
+	for _, address := range []types.Address{testAddress(0), testAddress(1)} {
+		txs, _ := store.GetTransactions(address)
+		if len(txs) != 1 {
+			t.Fatalf("Expected 1 transaction for %s, got %d", address, len(txs))
+		}
+		if txs[0].Confirmation != types.StatusConfirmed {
+			t.Errorf("Unexpected confirmation status: %s", txs[0].Confirmation)
+		}
+		if txs[0].BlockHash != "0xblock100" || txs[0].Timestamp != 0x64f0a000 {
+			t.Errorf("Unexpected block metadata: hash %s, timestamp %d", txs[0].BlockHash, txs[0].Timestamp)
+		}
+		if txs[0].Status != types.ReceiptSuccess || txs[0].TransactionFee.Cmp(big.NewInt(21000*1e9)) != 0 {
+			t.Errorf("Unexpected receipt fields: status %s, fee %v", txs[0].Status, txs[0].TransactionFee)
+		}
+	}
+
+	txs, _ := store.GetTransactions(testAddress(2))
+	if len(txs) != 0 {
+		t.Errorf("Expected no transactions for %s, got %d", testAddress(2), len(txs))
+	}
+}
+
+// BenchmarkMatchPerSubscriber measures the previous pipeline, which fetched
+// the block once for every subscribed address.
+func BenchmarkMatchPerSubscriber(b *testing.B) {
+	p, node, _ := newTestParser(b, 500)
+	ctx := context.Background()
+	addresses := make([]types.Address, 0, 500)
+	for address := range p.subscribers {
+		addresses = append(addresses, address)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, address := range addresses {
+			if _, err := p.client.GetTransactionsForAddress(ctx, address, 100); err != nil {
+				b.Fatalf("Unexpected error: %v", err)
+			}
+		}
+	}
+	b.ReportMetric(float64(node.callCount("eth_getBlockByNumber"))/float64(b.N), "rpc-calls/op")
+}
+
+// BenchmarkMatchBlockOnce measures fetching the block once and matching every
+// transaction against the subscriber set.
+func BenchmarkMatchBlockOnce(b *testing.B) {
+	p, node, _ := newTestParser(b, 500)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		block, err := p.fetchBlock(ctx, 100)
+		if err != nil {
+			b.Fatalf("Unexpected error: %v", err)
+		}
+		p.matchTransactions(block.Transactions)
+	}
+	b.ReportMetric(float64(node.callCount("eth_getBlockByNumber"))/float64(b.N), "rpc-calls/op")
+}
+
+func TestProcessBlockNotifiesOnce(t *testing.T) {
+	p, node, _ := newTestParser(t, 2)
+
+	block, err := p.fetchBlock(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// A retry of the block, e.g. after fetching its logs failed
+	for i := 0; i < 2; i++ {
+		if err := p.processBlock(context.Background(), block, types.StatusConfirmed); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// Sender and recipient of the matched transaction
+	if calls := node.callCount("webhook"); calls != 2 {
+		t.Errorf("Expected 2 notifications, got %d", calls)
+	}
+}
+
+func TestProcessBlockIndexesInternalTransfers(t *testing.T) {
+	p, node, store := newTestParser(t, 2)
+	p.config.TraceMode = TraceModeDebug
+
+	contract, other := testAddress(900), testAddress(901)
+	call := func(callType string, from, to types.Address, value string, calls ...map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"type": callType, "from": from.String(), "to": to.String(), "value": value, "calls": calls}
+	}
+
+	for range node.block["transactions"].([]map[string]string) {
+		node.traces = append(node.traces, map[string]interface{}{"result": map[string]interface{}{"type": "CALL"}})
+	}
+	reverted := call("CALL", contract, testAddress(1), "0x20", call("CALL", contract, testAddress(1), "0x30"))
+	reverted["error"] = "execution reverted"
+	node.traces[0]["result"] = call("CALL", testAddress(0), contract, "0x0",
+		call("CALL", contract, testAddress(1), "0x10"),
+		reverted,
+		call("DELEGATECALL", contract, testAddress(1), "0x40"),
+		call("CALL", contract, other, "0x0", call("CALL", other, testAddress(1), "0x50")),
+	)
+
+	block, err := p.fetchBlock(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := p.processBlock(context.Background(), block, types.StatusConfirmed); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	transfers, _ := store.GetInternalTransfers(testAddress(1))
+	if len(transfers) != 2 {
+		t.Fatalf("Expected 2 internal transfers, got %d", len(transfers))
+	}
+
+	expected := []struct {
+		depth int
+		from  types.Address
+		value int64
+	}{{1, contract, 0x10}, {2, other, 0x50}}
+	for i, want := range expected {
+		transfer := transfers[i]
+		if transfer.TransactionHash != fmt.Sprintf("0x%064x", 0) || transfer.TraceIndex != i || transfer.BlockHash != "0xblock100" {
+			t.Errorf("Unexpected transfer %d: %+v", i, transfer)
+		}
+		if transfer.Depth != want.depth || transfer.From != want.from || transfer.Value.Int64() != want.value {
+			t.Errorf("Transfer %d: expected depth %d from %s value %d, got %+v", i, want.depth, want.from, want.value, transfer)
+		}
+	}
+}
+
+func pendingTx(hash string, from, to types.Address, nonce string) map[string]string {
+	return map[string]string{
+		"hash": hash, "type": "0x2", "from": from.String(), "to": to.String(),
+		"value": "0x1", "nonce": nonce, "gas": "0x5208", "input": "0x",
+	}
+}
+
+func TestMempoolTransactionsAreReconciled(t *testing.T) {
+	p, node, store := newTestParser(t, 20)
+
+	// Mined as is in block 100
+	mined := fmt.Sprintf("0x%064x", 10)
+	// Replaced by the block's transaction 0 with the same sender and nonce
+	replaced := "0x" + strings.Repeat("ab", 32)
+	node.mempool = map[string]map[string]string{
+		mined:    pendingTx(mined, testAddress(10), testAddress(11), "0x0"),
+		replaced: pendingTx(replaced, testAddress(0), testAddress(5), "0x0"),
+	}
+
+	var watch mempoolWatch
+	p.pollMempool(context.Background(), &watch)
+
+	txs, _ := store.GetPendingTransactions(testAddress(0))
+	if len(txs) != 1 || txs[0].Status != types.PendingStatusPending {
+		t.Fatalf("Expected 1 pending transaction for %s, got %+v", testAddress(0), txs)
+	}
+
+	block, err := p.fetchBlock(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := p.processBlock(context.Background(), block, types.StatusConfirmed); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	txs, _ = store.GetPendingTransactions(testAddress(0))
+	if len(txs) != 1 || txs[0].Status != types.PendingStatusReplaced || txs[0].ReplacedBy != fmt.Sprintf("0x%064x", 0) {
+		t.Errorf("Expected replaced transaction, got %+v", txs)
+	}
+
+	// Both the sender and the recipient see the mined transaction
+	for _, address := range []types.Address{testAddress(10), testAddress(11)} {
+		txs, _ = store.GetPendingTransactions(address)
+		if len(txs) != 1 || txs[0].Status != types.PendingStatusMined || txs[0].BlockNumber != 100 {
+			t.Errorf("Expected mined transaction for %s, got %+v", address, txs)
+		}
+	}
+
+	if len(p.pending) != 0 {
+		t.Errorf("Expected no tracked transactions, got %d", len(p.pending))
+	}
+}
+
+func TestMempoolKeepsUnresolvedHashes(t *testing.T) {
+	p, node, store := newTestParser(t, 2)
+
+	hash := "0x" + strings.Repeat("cd", 32)
+	node.mempool = map[string]map[string]string{hash: pendingTx(hash, testAddress(0), testAddress(1), "0x0")}
+	node.mempoolDown = true
+
+	var watch mempoolWatch
+	p.pollMempool(context.Background(), &watch)
+	if len(watch.backlog) != 1 {
+		t.Fatalf("Expected the hash to be kept for the next poll, got %v", watch.backlog)
+	}
+
+	// The filter does not return the hash again
+	node.mempoolDown = false
+	p.pollMempool(context.Background(), &watch)
+
+	txs, _ := store.GetPendingTransactions(testAddress(0))
+	if len(txs) != 1 || txs[0].Transaction.Hash != hash {
+		t.Errorf("Expected pending transaction %s, got %+v", hash, txs)
+	}
+	if len(watch.backlog) != 0 {
+		t.Errorf("Expected an empty backlog, got %v", watch.backlog)
+	}
+}
+
+func TestCancellationAbortsInFlightCalls(t *testing.T) {
+	p, node, _ := newTestParser(t, 2)
+	node.mu.Lock()
+	node.delay = 10 * time.Second
+	node.mu.Unlock()
+
+	// An RPC call waiting on the node
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := p.GetCurrentBlock(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the call to return promptly, took %v", elapsed)
+	}
+
+	// A fetch loop with blocks in flight and blocks not yet started
+	p.config.FetchWorkers = 1
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start = time.Now()
+	for i, result := range p.fetchBlocks(ctx, 100, 103) {
+		if r := <-result; !errors.Is(r.err, context.Canceled) {
+			t.Errorf("Expected context.Canceled for block %d, got %v", 100+i, r.err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the fetch to stop promptly, took %v", elapsed)
+	}
+}
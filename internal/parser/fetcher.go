@@ -0,0 +1,58 @@
+package parser
+
+import (
+	"context"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// fetchResult is the outcome of downloading a single block
+type fetchResult struct {
+	block *types.Block
+	err   error
+}
+
+// fetchBlocks downloads the blocks from..to using a pool of FetchWorkers
+// concurrent fetchers. The i-th returned channel receives block from+i, so
+// callers can commit results strictly in block order regardless of the order
+// in which fetches complete. Cancelling ctx stops scheduling new fetches and fails the
+// blocks that were not scheduled yet.
+func (p *EthereumParser) fetchBlocks(ctx context.Context, from, to int64) []chan fetchResult {
+	if to < from {
+		return nil
+	}
+
+	results := make([]chan fetchResult, to-from+1)
+	for i := range results {
+		results[i] = make(chan fetchResult, 1)
+	}
+
+	jobs := make(chan int64)
+	workers := min(max(p.config.FetchWorkers, 1), len(results))
+	for w := 0; w < workers; w++ {
+		go func() {
+			for blockNumber := range jobs {
+				block, err := p.fetchBlock(ctx, blockNumber)
+				results[blockNumber-from] <- fetchResult{block: block, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for blockNumber := from; blockNumber <= to; blockNumber++ {
+			select {
+			case jobs <- blockNumber:
+			case <-ctx.Done():
+				// Fail the blocks that will not be fetched so callers
+				// waiting on them in order are not left hanging
+				for ; blockNumber <= to; blockNumber++ {
+					results[blockNumber-from] <- fetchResult{err: ctx.Err()}
+				}
+				return
+			}
+		}
+	}()
+
+	return results
+}
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum_parser/internal/config"
+	"github.com/ethereum_parser/internal/storage"
+)
+
+// chainTags are the block tags served by newChainParser
+var chainTags = map[string]int64{"latest": 99, "safe": 95, "finalized": 90}
+
+// newChainParser creates a parser on a node serving empty blocks 0-99.
+// Fetching block n takes delay(n).
+func newChainParser(t *testing.T, workers int, delay func(n int64) time.Duration) *EthereumParser {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+			ID     int           `json:"id"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		var result interface{} = "0x63"
+		if req.Method == "eth_getLogs" {
+			result = []interface{}{}
+		}
+		if req.Method == "eth_getBlockByNumber" {
+			n, ok := chainTags[req.Params[0].(string)]
+			if !ok {
+				n, _ = strconv.ParseInt(req.Params[0].(string)[2:], 16, 64)
+			}
+			select {
+			case <-time.After(delay(n)):
+			case <-r.Context().Done():
+				return
+			}
+			result = map[string]interface{}{
+				"number":       fmt.Sprintf("0x%x", n),
+				"hash":         fmt.Sprintf("0xblock%d", n),
+				"parentHash":   fmt.Sprintf("0xblock%d", n-1),
+				"timestamp":    "0x0",
+				"gasUsed":      "0x0",
+				"gasLimit":     "0x0",
+				"miner":        testAddress(0).String(),
+				"transactions": []interface{}{},
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.NewConfig()
+	cfg.EthereumRPCURL = server.URL
+	cfg.FetchWorkers = workers
+	p, err := NewEthereumParser(storage.NewMemoryStorage(), cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return p
+}
+
+func TestFetchBlocksReturnsBlocksInOrder(t *testing.T) {
+	// Later blocks complete first
+	p := newChainParser(t, 5, func(n int64) time.Duration {
+		return time.Duration(10-n) * 20 * time.Millisecond
+	})
+
+	results := p.fetchBlocks(context.Background(), 1, 5)
+	for i, ch := range results {
+		result := <-ch
+		if result.err != nil {
+			t.Fatalf("Unexpected error: %v", result.err)
+		}
+		if result.block.Number != int64(i+1) {
+			t.Errorf("Expected block %d at position %d, got %d", i+1, i, result.block.Number)
+		}
+	}
+}
+
+func TestFetchBlocksFailsUnscheduledBlocksOnCancel(t *testing.T) {
+	// Block 1 is fast, every later block outlasts the test
+	p := newChainParser(t, 1, func(n int64) time.Duration {
+		if n == 1 {
+			return 0
+		}
+		return time.Minute
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := p.fetchBlocks(ctx, 1, 10)
+	if result := <-results[0]; result.err != nil {
+		t.Fatalf("Unexpected error: %v", result.err)
+	}
+	cancel()
+
+	for i, ch := range results[1:] {
+		select {
+		case result := <-ch:
+			if result.err == nil {
+				t.Errorf("Expected an error for block %d", i+2)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Block %d was never delivered after cancellation", i+2)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// Trace modes for internal transfer indexing
+const (
+	// TraceModeDebug traces blocks with debug_traceBlockByNumber and the
+	// callTracer (geth and compatible nodes)
+	TraceModeDebug = "debug"
+	// TraceModeTrace traces blocks with trace_block (Erigon, Nethermind)
+	TraceModeTrace = "trace"
+)
+
+// isValidTraceMode accepts the empty mode, which disables tracing
+func isValidTraceMode(mode string) bool {
+	switch mode {
+	case "", TraceModeDebug, TraceModeTrace:
+		return true
+	}
+	return false
+}
+
+// fetchInternalTransfers traces a block and returns the value-bearing
+// internal calls in which one of the addresses is sender or recipient, keyed
+// by matched address. It returns no transfers when tracing is disabled.
+func (p *EthereumParser) fetchInternalTransfers(ctx context.Context, block *types.Block, addresses map[types.Address]bool) (map[types.Address][]types.InternalTransfer, error) {
+	matches := make(map[types.Address][]types.InternalTransfer)
+	if p.config.TraceMode == "" || len(addresses) == 0 || len(block.Transactions) == 0 {
+		return matches, nil
+	}
+
+	hashes := make([]string, len(block.Transactions))
+	inBlock := make(map[string]bool, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		hashes[i] = tx.Hash
+		inBlock[tx.Hash] = true
+	}
+
+	var transfers []types.InternalTransfer
+	var err error
+	switch p.config.TraceMode {
+	case TraceModeDebug:
+		transfers, err = p.client.DebugTraceBlock(ctx, block.Number, hashes)
+	case TraceModeTrace:
+		transfers, err = p.client.TraceBlock(ctx, block.Number)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to trace block %d: %w", block.Number, err)
+	}
+
+	for _, transfer := range transfers {
+		// Blocks are traced by number, so a reorg between fetching the
+		// block and tracing it shows up as unknown transactions
+		if !inBlock[transfer.TransactionHash] {
+			return nil, fmt.Errorf("trace of block %d contains unknown transaction %s", block.Number, transfer.TransactionHash)
+		}
+		transfer.BlockHash = block.Hash
+
+		if addresses[transfer.From] {
+			matches[transfer.From] = append(matches[transfer.From], transfer)
+		}
+		if transfer.To != "" && transfer.To != transfer.From && addresses[transfer.To] {
+			matches[transfer.To] = append(matches[transfer.To], transfer)
+		}
+	}
+
+	return matches, nil
+}
+
+// storeInternalTransfers persists matched internal transfers with the given
+// status and optionally sends webhook notifications for the new ones
+func (p *EthereumParser) storeInternalTransfers(matches map[types.Address][]types.InternalTransfer, status types.ConfirmationStatus, notify bool) error {
+	for address, transfers := range matches {
+		for _, transfer := range transfers {
+			transfer.Confirmation = status
+			stored, err := p.storage.StoreInternalTransfer(address, transfer)
+			if err != nil {
+				return fmt.Errorf("failed to store internal transfer %s:%d for %s: %v",
+					transfer.TransactionHash, transfer.TraceIndex, address, err)
+			}
+
+			if notify && stored {
+				p.notifyInternalTransfer(transfer, address, eventNewInternalTransfer, p.config.WebhookURL)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,361 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+const (
+	// mempoolBatchSize is the number of new mempool hashes resolved per
+	// batch request
+	mempoolBatchSize = 100
+	// maxMempoolBacklog bounds the hashes carried over to the next poll
+	maxMempoolBacklog = 10000
+)
+
+// mempoolWatch is the state of the mempool watcher between polls
+type mempoolWatch struct {
+	filterID string
+	// backlog holds hashes taken from the filter that were not processed
+	// yet; the filter does not return them again
+	backlog []string
+}
+
+// pendingNotification is a webhook notification collected while holding
+// p.pendingMu, to be sent after releasing it
+type pendingNotification struct {
+	tx      types.PendingTransaction
+	address types.Address
+}
+
+// pendingEntry is an unresolved mempool transaction of one or more
+// subscribed addresses
+type pendingEntry struct {
+	tx        types.PendingTransaction
+	addresses []types.Address
+	// lastChecked is when the node last confirmed it still knows the
+	// transaction
+	lastChecked time.Time
+}
+
+// senderNonce identifies the slot a transaction occupies in its sender's
+// nonce sequence; a mined transaction in the same slot replaces it
+func senderNonce(tx types.Transaction) string {
+	return fmt.Sprintf("%s:%d", tx.From, tx.Nonce)
+}
+
+// loadPending restores the unresolved mempool transactions from storage
+func (p *EthereumParser) loadPending() error {
+	stored, err := p.storage.GetPendingTransactionsByStatus(types.PendingStatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to load pending transactions: %v", err)
+	}
+
+	p.pendingMu.Lock()
+	defer p.pendingMu.Unlock()
+
+	for address, txs := range stored {
+		for _, tx := range txs {
+			entry, ok := p.pending[tx.Transaction.Hash]
+			if !ok {
+				entry = &pendingEntry{tx: tx, lastChecked: time.Unix(tx.FirstSeen, 0)}
+				p.pending[tx.Transaction.Hash] = entry
+				p.pendingNonces[senderNonce(tx.Transaction)] = tx.Transaction.Hash
+			}
+			entry.addresses = append(entry.addresses, address)
+		}
+	}
+	return nil
+}
+
+func (p *EthereumParser) watchMempool(ctx context.Context, stop <-chan struct{}) {
+	ticker := time.NewTicker(p.config.MempoolInterval)
+	defer ticker.Stop()
+
+	var watch mempoolWatch
+	defer func() {
+		if watch.filterID != "" {
+			cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+			defer cancel()
+			p.client.UninstallFilter(cleanupCtx, watch.filterID)
+		}
+	}()
+
+	for {
+		p.pollMempool(ctx, &watch)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollMempool collects the transactions that entered the mempool since the
+// previous poll and checks long-pending transactions for being dropped. The
+// filter is recreated when the node has expired it. Hashes that could not
+// be resolved in time are kept for the next poll.
+func (p *EthereumParser) pollMempool(parent context.Context, watch *mempoolWatch) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(parent), p.config.PollTimeout)
+	defer cancel()
+
+	if watch.filterID == "" {
+		id, err := p.client.NewPendingTransactionFilter(ctx)
+		if err != nil {
+			log.Printf("Failed to watch mempool: %v", err)
+			return
+		}
+		watch.filterID = id
+	}
+
+	hashes, err := p.client.GetFilterChanges(ctx, watch.filterID)
+	if err != nil {
+		log.Printf("Failed to poll mempool: %v", err)
+		watch.filterID = ""
+		return
+	}
+	watch.backlog = append(watch.backlog, hashes...)
+	if excess := len(watch.backlog) - maxMempoolBacklog; excess > 0 {
+		log.Printf("Mempool backlog full, skipping %d transactions", excess)
+		watch.backlog = watch.backlog[excess:]
+	}
+
+	subscribers := p.subscriberSet()
+	if len(subscribers) == 0 {
+		watch.backlog = nil
+	}
+	for len(watch.backlog) > 0 && parent.Err() == nil {
+		n := min(len(watch.backlog), mempoolBatchSize)
+		if err := p.trackPending(ctx, watch.backlog[:n], subscribers); err != nil {
+			log.Printf("Failed to track pending transactions, %d left for the next poll: %v", len(watch.backlog), err)
+			break
+		}
+		watch.backlog = watch.backlog[n:]
+	}
+
+	if err := p.checkDropped(ctx); err != nil {
+		log.Printf("Failed to check pending transactions: %v", err)
+	}
+}
+
+// trackPending resolves a batch of mempool hashes and stores the
+// transactions that involve a subscribed address
+func (p *EthereumParser) trackPending(ctx context.Context, hashes []string, subscribers map[types.Address]bool) error {
+	p.pendingMu.Lock()
+	var untracked []string
+	for _, hash := range hashes {
+		if _, tracked := p.pending[hash]; !tracked {
+			untracked = append(untracked, hash)
+		}
+	}
+	p.pendingMu.Unlock()
+	if len(untracked) == 0 {
+		return nil
+	}
+
+	txs, err := p.client.GetTransactionsByHash(ctx, untracked)
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		// Already gone, or mined before we got to it
+		if tx == nil || tx.BlockHash != "" {
+			continue
+		}
+		if err := p.trackTransaction(*tx, subscribers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trackTransaction starts tracking a mempool transaction if it involves a
+// subscribed address
+func (p *EthereumParser) trackTransaction(tx types.Transaction, subscribers map[types.Address]bool) error {
+	var addresses []types.Address
+	if subscribers[tx.From] {
+		addresses = append(addresses, tx.From)
+	}
+	if tx.To != "" && tx.To != tx.From && subscribers[tx.To] {
+		addresses = append(addresses, tx.To)
+	}
+	if len(addresses) == 0 {
+		return nil
+	}
+
+	entry := &pendingEntry{
+		tx: types.PendingTransaction{
+			Transaction: tx,
+			Status:      types.PendingStatusPending,
+			FirstSeen:   time.Now().Unix(),
+		},
+		addresses:   addresses,
+		lastChecked: time.Now(),
+	}
+	for _, address := range addresses {
+		if err := p.storage.SavePendingTransaction(address, entry.tx); err != nil {
+			return fmt.Errorf("failed to store pending transaction for %s: %v", address, err)
+		}
+		p.notifyPendingTransaction(entry.tx, address, eventNewPendingTransaction, p.config.WebhookURL)
+	}
+
+	p.pendingMu.Lock()
+	p.pending[tx.Hash] = entry
+	p.pendingNonces[senderNonce(tx)] = tx.Hash
+	p.pendingMu.Unlock()
+	return nil
+}
+
+// checkDropped asks the node about transactions that have been pending for
+// longer than PendingDropTimeout and marks the ones it no longer knows as
+// dropped
+func (p *EthereumParser) checkDropped(ctx context.Context) error {
+	now := time.Now()
+
+	p.pendingMu.Lock()
+	var stale []string
+	for hash, entry := range p.pending {
+		if now.Sub(entry.lastChecked) >= p.config.PendingDropTimeout {
+			stale = append(stale, hash)
+		}
+	}
+	p.pendingMu.Unlock()
+
+	for _, hash := range stale {
+		tx, err := p.client.GetTransactionByHash(ctx, hash)
+		if err != nil {
+			return err
+		}
+
+		var notifications []pendingNotification
+		p.pendingMu.Lock()
+		entry, ok := p.pending[hash]
+		switch {
+		case !ok:
+			// Resolved by block processing in the meantime
+		case tx == nil:
+			notifications, err = p.resolvePending(entry, types.PendingStatusDropped, 0, "")
+		default:
+			// Still in the mempool, or mined and about to be reconciled
+			entry.lastChecked = now
+		}
+		p.pendingMu.Unlock()
+
+		p.sendPendingNotifications(notifications)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcilePending resolves the tracked mempool transactions that a block
+// mined or replaced. Webhooks are sent after releasing p.pendingMu so a
+// slow webhook does not stall the mempool watcher.
+func (p *EthereumParser) reconcilePending(block *types.Block) error {
+	var notifications []pendingNotification
+	var err error
+
+	p.pendingMu.Lock()
+	for _, tx := range block.Transactions {
+		var resolved []pendingNotification
+		if entry, ok := p.pending[tx.Hash]; ok {
+			resolved, err = p.resolvePending(entry, types.PendingStatusMined, block.Number, "")
+		} else if hash, ok := p.pendingNonces[senderNonce(tx)]; ok {
+			resolved, err = p.resolvePending(p.pending[hash], types.PendingStatusReplaced, block.Number, tx.Hash)
+		}
+		notifications = append(notifications, resolved...)
+		if err != nil {
+			break
+		}
+	}
+	p.pendingMu.Unlock()
+
+	p.sendPendingNotifications(notifications)
+	return err
+}
+
+// resolvePending records the final status of a mempool transaction and
+// stops tracking it. It returns the notifications to send once the caller,
+// who must hold p.pendingMu, has released it.
+func (p *EthereumParser) resolvePending(entry *pendingEntry, status types.PendingStatus, blockNumber int64, replacedBy string) ([]pendingNotification, error) {
+	resolved := entry.tx
+	resolved.Status = status
+	resolved.ReplacedBy = replacedBy
+	if status == types.PendingStatusMined {
+		resolved.BlockNumber = blockNumber
+	}
+
+	var notifications []pendingNotification
+	for _, address := range entry.addresses {
+		if err := p.storage.SavePendingTransaction(address, resolved); err != nil {
+			return notifications, fmt.Errorf("failed to update pending transaction %s for %s: %v", resolved.Transaction.Hash, address, err)
+		}
+		notifications = append(notifications, pendingNotification{tx: resolved, address: address})
+	}
+
+	delete(p.pending, resolved.Transaction.Hash)
+	if p.pendingNonces[senderNonce(resolved.Transaction)] == resolved.Transaction.Hash {
+		delete(p.pendingNonces, senderNonce(resolved.Transaction))
+	}
+	return notifications, nil
+}
+
+// revertMined returns the mempool transactions mined in a block from
+// fromBlock onwards to pending and tracks them again. The reorg removed
+// their block, so they are either mined again on the canonical chain or
+// dropped.
+func (p *EthereumParser) revertMined(fromBlock int64) error {
+	mined, err := p.storage.GetPendingTransactionsByStatus(types.PendingStatusMined)
+	if err != nil {
+		return fmt.Errorf("failed to load mined transactions: %v", err)
+	}
+
+	var notifications []pendingNotification
+	p.pendingMu.Lock()
+	for address, txs := range mined {
+		for _, tx := range txs {
+			if tx.BlockNumber < fromBlock {
+				continue
+			}
+
+			tx.Status = types.PendingStatusPending
+			tx.BlockNumber = 0
+			if err = p.storage.SavePendingTransaction(address, tx); err != nil {
+				err = fmt.Errorf("failed to update pending transaction %s for %s: %v", tx.Transaction.Hash, address, err)
+				break
+			}
+			notifications = append(notifications, pendingNotification{tx: tx, address: address})
+
+			entry, ok := p.pending[tx.Transaction.Hash]
+			if !ok {
+				entry = &pendingEntry{tx: tx, lastChecked: time.Now()}
+				p.pending[tx.Transaction.Hash] = entry
+				p.pendingNonces[senderNonce(tx.Transaction)] = tx.Transaction.Hash
+			}
+			entry.addresses = append(entry.addresses, address)
+		}
+		if err != nil {
+			break
+		}
+	}
+	p.pendingMu.Unlock()
+
+	p.sendPendingNotifications(notifications)
+	return err
+}
+
+// sendPendingNotifications sends the notifications about resolved mempool
+// transactions
+func (p *EthereumParser) sendPendingNotifications(notifications []pendingNotification) {
+	for _, n := range notifications {
+		p.notifyPendingTransaction(n.tx, n.address, eventPendingTransactionUpdated, p.config.WebhookURL)
+	}
+}
@@ -0,0 +1,318 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ethereum_parser/internal/ethereum"
+	"github.com/ethereum_parser/internal/types"
+)
+
+// cleanupTimeout bounds the calls that release node resources on shutdown
+const cleanupTimeout = 5 * time.Second
+
+// blockNotFoundDelay is the wait before fetching again a block the node does
+// not know yet
+const blockNotFoundDelay = time.Second
+
+// Start launches block polling, and mempool watching if enabled, in the
+// background. Both stop when ctx is cancelled or Stop is called.
+func (p *EthereumParser) Start(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.running {
+		return fmt.Errorf("parser is already running")
+	}
+
+	checkpoint, err := p.storage.GetCheckpoint()
+	if err != nil {
+		return fmt.Errorf("failed to load checkpoint: %v", err)
+	}
+	if checkpoint != nil {
+		log.Printf("Resuming from checkpoint at block %d (%s)", checkpoint.BlockNumber, checkpoint.BlockHash)
+	}
+	p.checkpoint = checkpoint
+	p.window, err = p.loadWindow()
+	if err != nil {
+		return err
+	}
+	p.confirmation = confirmationState{confirmedThrough: -1, finalizedThrough: -1}
+	if checkpoint != nil {
+		p.confirmation.confirmedThrough = checkpoint.ConfirmedThrough
+		p.confirmation.finalizedThrough = checkpoint.FinalizedThrough
+	}
+
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	p.running = true
+
+	p.backfillCtx, p.cancelBackfill = context.WithCancel(ctx)
+	if err := p.resumeBackfills(); err != nil {
+		log.Printf("Failed to resume backfill jobs: %v", err)
+	}
+
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func(stop chan struct{}) {
+		defer workers.Done()
+		p.startBlockPolling(ctx, stop)
+	}(p.stop)
+
+	if p.config.MempoolWatch {
+		if err := p.loadPending(); err != nil {
+			log.Printf("Failed to restore pending transactions: %v", err)
+		}
+
+		workers.Add(1)
+		go func(stop chan struct{}) {
+			defer workers.Done()
+			p.watchMempool(ctx, stop)
+		}(p.stop)
+	}
+
+	go func(done chan struct{}) {
+		workers.Wait()
+		close(done)
+	}(p.done)
+
+	return nil
+}
+
+// Stop signals the poller and backfill jobs to exit and waits for the blocks
+// being processed to finish. It is safe to call Stop more than once.
+func (p *EthereumParser) Stop() {
+	p.mu.Lock()
+	if !p.running {
+		p.mu.Unlock()
+		return
+	}
+	p.running = false
+	close(p.stop)
+	p.cancelBackfill()
+	done := p.done
+	p.mu.Unlock()
+
+	<-done
+	p.backfills.Wait()
+}
+
+// startBlockPolling drives block processing from newHeads notifications
+// when the endpoint supports subscriptions and by polling every
+// PollInterval otherwise
+func (p *EthereumParser) startBlockPolling(ctx context.Context, stop <-chan struct{}) {
+	if p.followNewHeads(ctx, stop) {
+		return
+	}
+
+	ticker := time.NewTicker(p.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going without waiting for the ticker while catching up
+		for p.pollOnce(ctx, stop) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollOnce processes at most CatchupBatchSize blocks after the checkpoint and
+// reports whether more blocks are waiting to be processed. Shutdown is only
+// checked between blocks so an in-flight block is always allowed to finish.
+func (p *EthereumParser) pollOnce(parent context.Context, stop <-chan struct{}) bool {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(parent), p.config.PollTimeout)
+	defer cancel()
+
+	currentBlock, err := p.GetCurrentBlock(ctx)
+	if err != nil {
+		log.Printf("Failed to get current block: %v", err)
+		return false
+	}
+
+	state, err := p.loadConfirmationState(ctx, currentBlock)
+	if err != nil {
+		log.Printf("Failed to resolve confirmation policy: %v", err)
+		return false
+	}
+
+	if err := p.updateConfirmations(state); err != nil {
+		log.Printf("Failed to update confirmation status: %v", err)
+		return false
+	}
+
+	// Without a checkpoint, start indexing from the newest indexable block
+	if p.checkpoint == nil {
+		header, err := p.client.GetBlockHeader(ctx, state.indexThrough)
+		if err != nil {
+			log.Printf("Failed to get block %d: %v", state.indexThrough, err)
+			return false
+		}
+		if err := p.advanceCheckpoint(*header); err != nil {
+			log.Printf("Failed to initialize checkpoint: %v", err)
+		}
+		return false
+	}
+
+	batchSize := max(p.config.CatchupBatchSize, 1)
+
+	target := state.indexThrough
+	if batchEnd := p.checkpoint.BlockNumber + int64(batchSize); batchEnd < target {
+		target = batchEnd
+	}
+
+	// Blocks are fetched concurrently but committed strictly in order so the
+	// checkpoint and reorg detection always see a contiguous chain
+	first := p.checkpoint.BlockNumber + 1
+	fetchCtx, cancelFetch := context.WithCancel(ctx)
+	defer cancelFetch()
+	results := p.fetchBlocks(fetchCtx, first, target)
+
+	for blockNumber := first; blockNumber <= target; blockNumber++ {
+		select {
+		case <-parent.Done():
+			return false
+		case <-stop:
+			return false
+		default:
+		}
+
+		result := <-results[blockNumber-first]
+		if errors.Is(result.err, ethereum.ErrBlockNotFound) {
+			// The node announced a head it cannot serve yet, typically a
+			// backend behind a load balancer lagging behind the others. Retry
+			// shortly instead of waiting for the next poll or head.
+			log.Printf("Block %d not available yet, retrying in %v", blockNumber, blockNotFoundDelay)
+			select {
+			case <-parent.Done():
+				return false
+			case <-stop:
+				return false
+			case <-time.After(blockNotFoundDelay):
+			}
+			return true
+		}
+		if result.err != nil {
+			log.Printf("Failed to get block %d: %v", blockNumber, result.err)
+			return false
+		}
+		block := result.block
+
+		if block.ParentHash != p.checkpoint.BlockHash {
+			if err := p.handleReorg(ctx); err != nil {
+				log.Printf("Failed to handle reorg at block %d: %v", blockNumber, err)
+				return false
+			}
+			// Re-index from the rewound checkpoint right away
+			return true
+		}
+
+		if err := p.processBlock(ctx, block, state.statusOf(blockNumber)); err != nil {
+			log.Printf("Failed to process block %d: %v", blockNumber, err)
+			return false
+		}
+
+		if err := p.advanceCheckpoint(block.BlockHeader); err != nil {
+			log.Printf("Failed to save checkpoint for block %d: %v", blockNumber, err)
+			return false
+		}
+	}
+
+	if target < state.indexThrough {
+		log.Printf("Caught up to block %d, %d blocks behind", target, state.indexThrough-target)
+		return true
+	}
+	return false
+}
+
+// fetchBlock downloads a block with its transactions. Transient failures
+// are retried by the client with backoff until ctx expires.
+func (p *EthereumParser) fetchBlock(ctx context.Context, blockNumber int64) (*types.Block, error) {
+	return p.client.GetBlock(ctx, blockNumber)
+}
+
+// advanceCheckpoint persists header as the last fully processed block
+func (p *EthereumParser) advanceCheckpoint(header types.BlockHeader) error {
+	checkpoint := p.checkpointAt(header)
+	if err := p.storage.SaveCheckpoint(checkpoint); err != nil {
+		return err
+	}
+
+	p.checkpoint = &checkpoint
+	p.window.add(header)
+	return nil
+}
+
+// followNewHeads processes blocks as newHeads notifications arrive,
+// resubscribing after the connection is lost and catching up on the blocks
+// missed in between. It returns false without processing anything if the
+// endpoint does not support subscriptions, and true once stopped.
+func (p *EthereumParser) followNewHeads(ctx context.Context, stop <-chan struct{}) bool {
+	heads := make(chan types.BlockHeader, 1)
+
+	for {
+		sub, err := p.client.SubscribeNewHeads(ctx, heads)
+		if errors.Is(err, ethereum.ErrSubscriptionsUnsupported) {
+			log.Printf("New heads subscription unavailable, polling every %s: %v", p.config.PollInterval, err)
+			return false
+		}
+		if err != nil {
+			log.Printf("Failed to subscribe to new heads: %v", err)
+
+			// Keep indexing by polling until the subscription is restored
+			for p.pollOnce(ctx, stop) {
+			}
+			select {
+			case <-ctx.Done():
+				return true
+			case <-stop:
+				return true
+			case <-time.After(p.config.PollInterval):
+			}
+			continue
+		}
+		log.Printf("Subscribed to new heads")
+
+		// Fill the gap left while the subscription was down
+		for p.pollOnce(ctx, stop) {
+		}
+
+		for subscribed := true; subscribed; {
+			select {
+			case <-ctx.Done():
+				p.unsubscribe(ctx, sub)
+				return true
+			case <-stop:
+				p.unsubscribe(ctx, sub)
+				return true
+			case <-heads:
+				for p.pollOnce(ctx, stop) {
+				}
+			case err := <-sub.Err():
+				log.Printf("New heads subscription lost: %v", err)
+				subscribed = false
+			}
+		}
+	}
+}
+
+// unsubscribe cancels a subscription on the way out, even when ctx is
+// already cancelled
+func (p *EthereumParser) unsubscribe(ctx context.Context, sub *ethereum.Subscription) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+	defer cancel()
+
+	if err := p.client.Unsubscribe(ctx, sub); err != nil {
+		log.Printf("Failed to unsubscribe from new heads: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ethereum_parser/internal/storage"
+	"github.com/ethereum_parser/internal/types"
+)
+
+// waitForGoroutines waits until at most n goroutines are left running
+func waitForGoroutines(t *testing.T, n int) {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+		if runtime.NumGoroutine() <= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("Expected at most %d goroutines, got %d:\n%s", n, runtime.NumGoroutine(), buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStartTwiceIsRejected(t *testing.T) {
+	p := newChainParser(t, 1, func(int64) time.Duration { return 0 })
+
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer p.Stop()
+
+	if err := p.Start(context.Background()); err == nil {
+		t.Errorf("Expected starting a running parser to fail")
+	}
+}
+
+func TestStopWaitsForPolling(t *testing.T) {
+	p := newChainParser(t, 1, func(int64) time.Duration { return 0 })
+	baseline := runtime.NumGoroutine()
+
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Let the first poll cycle initialize the checkpoint
+	waitForCheckpoint(t, p.storage, 0)
+
+	p.Stop()
+	select {
+	case <-p.done:
+	default:
+		t.Fatalf("Expected Stop to wait for the polling goroutine")
+	}
+	waitForGoroutines(t, baseline)
+
+	// Stopping again is a no-op, and the parser can be started anew
+	p.Stop()
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	p.Stop()
+	waitForGoroutines(t, baseline)
+}
+
+func TestStopBeforeStart(t *testing.T) {
+	p := newChainParser(t, 1, func(int64) time.Duration { return 0 })
+	p.Stop()
+
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	p.Stop()
+}
+
+func TestStartResumesFromCheckpoint(t *testing.T) {
+	fetched := &fetchLog{blocks: make(map[int64]int)}
+	chain := newChainParser(t, 1, func(n int64) time.Duration {
+		fetched.record(n)
+		return 0
+	})
+	cfg := chain.config
+	cfg.ConfirmationPolicy = PolicyConfirmations
+	cfg.Confirmations = 5
+
+	dir := t.TempDir()
+	ds, err := storage.NewDiskStorage(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := ds.SaveCheckpoint(types.Checkpoint{BlockNumber: 90, BlockHash: "0xblock90"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The first run indexes up to block 94, five blocks below the head
+	p, err := NewEthereumParser(ds, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	waitForCheckpoint(t, ds, 94)
+	p.Stop()
+	if err := ds.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	before := make(map[int64]int)
+	for n := int64(91); n <= 99; n++ {
+		before[n] = fetched.count(n)
+	}
+
+	// The second run picks up at the next block instead of the head
+	ds, err = storage.NewDiskStorage(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer ds.Close()
+
+	cfg.Confirmations = 0
+	p, err = NewEthereumParser(ds, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	waitForCheckpoint(t, ds, 99)
+	p.Stop()
+
+	for n := int64(91); n <= 94; n++ {
+		if fetched.count(n) != before[n] {
+			t.Errorf("Expected block %d not to be fetched again after the restart", n)
+		}
+	}
+	for n := int64(95); n <= 99; n++ {
+		if fetched.count(n) == before[n] {
+			t.Errorf("Expected block %d to be fetched after the restart", n)
+		}
+	}
+}
+
+// waitForCheckpoint waits until the checkpoint in s reaches block n
+func waitForCheckpoint(t *testing.T, s storage.Storage, n int64) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if checkpoint, _ := s.GetCheckpoint(); checkpoint != nil && checkpoint.BlockNumber >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for the checkpoint to reach block %d", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
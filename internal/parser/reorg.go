@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// blockWindow keeps the headers of the most recently processed blocks so a
+// diverging canonical chain can be traced back to the common ancestor
+type blockWindow struct {
+	size    int
+	headers []types.BlockHeader
+}
+
+func newBlockWindow(size int) *blockWindow {
+	return &blockWindow{size: max(size, 1)}
+}
+
+// add appends the header of the next processed block, evicting the oldest
+// entry once the window is full. Headers that do not extend the window
+// reset it.
+func (w *blockWindow) add(header types.BlockHeader) {
+	if n := len(w.headers); n > 0 && w.headers[n-1].Number+1 != header.Number {
+		w.headers = w.headers[:0]
+	}
+
+	w.headers = append(w.headers, header)
+	if len(w.headers) > w.size {
+		w.headers = w.headers[len(w.headers)-w.size:]
+	}
+}
+
+func (w *blockWindow) get(number int64) (types.BlockHeader, bool) {
+	if len(w.headers) == 0 {
+		return types.BlockHeader{}, false
+	}
+
+	i := number - w.headers[0].Number
+	if i < 0 || i >= int64(len(w.headers)) {
+		return types.BlockHeader{}, false
+	}
+	return w.headers[i], true
+}
+
+// truncate drops every header above number
+func (w *blockWindow) truncate(number int64) {
+	for len(w.headers) > 0 && w.headers[len(w.headers)-1].Number > number {
+		w.headers = w.headers[:len(w.headers)-1]
+	}
+}
+
+// loadWindow seeds the window with the checkpoints saved before a restart so
+// a reorg deeper than the last processed block can still be traced back
+func (p *EthereumParser) loadWindow() (*blockWindow, error) {
+	checkpoints, err := p.storage.GetCheckpoints()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoints: %v", err)
+	}
+
+	window := newBlockWindow(p.config.ReorgWindow)
+	for _, cp := range checkpoints {
+		window.add(types.BlockHeader{Number: cp.BlockNumber, Hash: cp.BlockHash})
+	}
+	return window, nil
+}
+
+// handleReorg is called when the block after the checkpoint does not build on
+// it. It finds the last block shared with the canonical chain, removes every
+// transaction and token transfer indexed above it, returns the mempool
+// transactions mined above it to pending and rewinds the checkpoint so the
+// new canonical blocks are indexed by the next poll cycle.
+func (p *EthereumParser) handleReorg(ctx context.Context) error {
+	ancestor, err := p.findCommonAncestor(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Chain reorganization detected: rolling back from block %d to %d",
+		p.checkpoint.BlockNumber, ancestor.Number)
+
+	removed, err := p.storage.RemoveTransactionsFrom(ancestor.Number + 1)
+	if err != nil {
+		return fmt.Errorf("failed to remove orphaned transactions: %v", err)
+	}
+
+	for address, txs := range removed {
+		for _, tx := range txs {
+			p.notifyTransaction(tx, address, eventReorged, p.config.WebhookURL)
+		}
+	}
+
+	removedTransfers, err := p.storage.RemoveTokenTransfersFrom(ancestor.Number + 1)
+	if err != nil {
+		return fmt.Errorf("failed to remove orphaned token transfers: %v", err)
+	}
+
+	for address, transfers := range removedTransfers {
+		for _, transfer := range transfers {
+			p.notifyTokenTransfer(transfer, address, eventReorged, p.config.WebhookURL)
+		}
+	}
+
+	removedInternal, err := p.storage.RemoveInternalTransfersFrom(ancestor.Number + 1)
+	if err != nil {
+		return fmt.Errorf("failed to remove orphaned internal transfers: %v", err)
+	}
+
+	for address, transfers := range removedInternal {
+		for _, transfer := range transfers {
+			p.notifyInternalTransfer(transfer, address, eventReorged, p.config.WebhookURL)
+		}
+	}
+
+	if err := p.revertMined(ancestor.Number + 1); err != nil {
+		return err
+	}
+
+	checkpoint := p.checkpointAt(*ancestor)
+	if err := p.storage.SaveCheckpoint(checkpoint); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %v", err)
+	}
+
+	p.checkpoint = &checkpoint
+	p.window.truncate(ancestor.Number)
+	if _, ok := p.window.get(ancestor.Number); !ok {
+		p.window.add(*ancestor)
+	}
+
+	return nil
+}
+
+// findCommonAncestor walks back from the checkpoint comparing the recorded
+// block hashes with the canonical chain. If the fork is deeper than the
+// window, the block just below the window is used, and the block below the
+// checkpoint when the window does not reach the checkpoint at all: the
+// checkpoint block is orphaned, so it can never be the ancestor.
+func (p *EthereumParser) findCommonAncestor(ctx context.Context) (*types.BlockHeader, error) {
+	number := p.checkpoint.BlockNumber
+	for ; ; number-- {
+		known, ok := p.window.get(number)
+		if !ok {
+			break
+		}
+
+		canonical, err := p.client.GetBlockHeader(ctx, number)
+		if err != nil {
+			return nil, err
+		}
+		if canonical.Hash == known.Hash {
+			return canonical, nil
+		}
+	}
+
+	number = max(min(number, p.checkpoint.BlockNumber-1), 0)
+	log.Printf("Reorg is deeper than the tracked window, rolling back to block %d", number)
+
+	return p.client.GetBlockHeader(ctx, number)
+}
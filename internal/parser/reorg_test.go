@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+func TestHandleReorgAfterRestart(t *testing.T) {
+	p := newChainParser(t, 1, func(n int64) time.Duration { return 0 })
+
+	// Blocks 86-90 were indexed on a fork the canonical chain later replaced
+	for n := int64(80); n <= 90; n++ {
+		hash := fmt.Sprintf("0xblock%d", n)
+		if n > 85 {
+			hash = fmt.Sprintf("0xfork%d", n)
+		}
+		if err := p.storage.SaveCheckpoint(types.Checkpoint{BlockNumber: n, BlockHash: hash}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// Restore the state the parser starts with
+	window, err := p.loadWindow()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	p.window = window
+	p.checkpoint, _ = p.storage.GetCheckpoint()
+
+	if err := p.handleReorg(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	checkpoint, err := p.storage.GetCheckpoint()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if checkpoint.BlockNumber != 85 || checkpoint.BlockHash != "0xblock85" {
+		t.Errorf("Expected rollback to block 85, got %+v", checkpoint)
+	}
+}
+
+func TestHandleReorgWithEmptyWindow(t *testing.T) {
+	p := newChainParser(t, 1, func(n int64) time.Duration { return 0 })
+
+	// The checkpoint block was orphaned and nothing else is known
+	p.window = newBlockWindow(p.config.ReorgWindow)
+	p.checkpoint = &types.Checkpoint{BlockNumber: 90, BlockHash: "0xfork90"}
+
+	address := testAddress(1)
+	if _, err := p.storage.StoreTransaction(address, types.Transaction{Hash: "0x1", BlockNumber: 90}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := p.handleReorg(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	checkpoint, _ := p.storage.GetCheckpoint()
+	if checkpoint.BlockNumber != 89 || checkpoint.BlockHash != "0xblock89" {
+		t.Errorf("Expected rollback to block 89, got %+v", checkpoint)
+	}
+	if txs, _ := p.storage.GetTransactions(address); len(txs) != 0 {
+		t.Errorf("Expected the orphaned transaction to be removed, got %d", len(txs))
+	}
+}
+
+func TestHandleReorgRevertsMinedPendingTransactions(t *testing.T) {
+	p := newChainParser(t, 1, func(n int64) time.Duration { return 0 })
+	p.window = newBlockWindow(p.config.ReorgWindow)
+	p.checkpoint = &types.Checkpoint{BlockNumber: 90, BlockHash: "0xfork90"}
+
+	from, to := testAddress(1), testAddress(2)
+	orphaned := types.PendingTransaction{
+		Transaction: types.Transaction{Hash: "0xorphaned", From: from, To: to, Nonce: 7},
+		Status:      types.PendingStatusMined,
+		BlockNumber: 90,
+	}
+	kept := types.PendingTransaction{
+		Transaction: types.Transaction{Hash: "0xkept", From: from, Nonce: 6},
+		Status:      types.PendingStatusMined,
+		BlockNumber: 89,
+	}
+	for _, address := range []types.Address{from, to} {
+		if err := p.storage.SavePendingTransaction(address, orphaned); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := p.storage.SavePendingTransaction(from, kept); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := p.handleReorg(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, address := range []types.Address{from, to} {
+		txs, _ := p.storage.GetPendingTransactions(address)
+		for _, tx := range txs {
+			switch tx.Transaction.Hash {
+			case orphaned.Transaction.Hash:
+				if tx.Status != types.PendingStatusPending || tx.BlockNumber != 0 {
+					t.Errorf("Expected the orphaned transaction to be pending again for %s, got %+v", address, tx)
+				}
+			case kept.Transaction.Hash:
+				if tx.Status != types.PendingStatusMined || tx.BlockNumber != 89 {
+					t.Errorf("Expected the transaction below the fork to stay mined, got %+v", tx)
+				}
+			}
+		}
+	}
+
+	// It is tracked again so the canonical block that includes it resolves it
+	entry, ok := p.pending[orphaned.Transaction.Hash]
+	if !ok || len(entry.addresses) != 2 {
+		t.Fatalf("Expected the orphaned transaction to be tracked for both addresses, got %+v", entry)
+	}
+	if err := p.reconcilePending(&types.Block{
+		BlockHeader:  types.BlockHeader{Number: 90, Hash: "0xblock90"},
+		Transactions: []types.Transaction{orphaned.Transaction},
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	txs, _ := p.storage.GetPendingTransactionsByStatus(types.PendingStatusMined)
+	if len(txs[to]) != 1 || txs[to][0].BlockNumber != 90 {
+		t.Errorf("Expected the transaction to be mined again, got %+v", txs[to])
+	}
+}
@@ -0,0 +1,266 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// Event signatures of the supported token transfer logs
+const (
+	// transferTopic is keccak256("Transfer(address,address,uint256)"), shared
+	// by ERC-20 and ERC-721 (where the third argument is an indexed token ID)
+	transferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+	// transferSingleTopic is keccak256("TransferSingle(address,address,address,uint256,uint256)")
+	transferSingleTopic = "0xc3d58168c5ae7397731d063d5bbf3d657854427343f4c083240f7aacaa2d0f62"
+	// transferBatchTopic is keccak256("TransferBatch(address,address,address,uint256[],uint256[])")
+	transferBatchTopic = "0x4a39dc06d4c0dbc64b70af90fd698a233a518aa5d07e595d983b8c0526c8f7fb"
+)
+
+// addressTopic left-pads an address to a 32-byte log topic
+func addressTopic(address types.Address) string {
+	return "0x000000000000000000000000" + strings.TrimPrefix(address.String(), "0x")
+}
+
+// topicAddress extracts the address stored in a 32-byte log topic
+func topicAddress(topic string) (types.Address, error) {
+	if len(topic) != 66 {
+		return "", fmt.Errorf("invalid address topic %q", topic)
+	}
+	return types.ParseAddress("0x" + topic[26:])
+}
+
+// fetchTokenTransfers retrieves the ERC-20, ERC-721 and ERC-1155 transfer
+// logs of a block in which one of the addresses is sender or recipient,
+// keyed by matched address. Logs are selected by block hash so they always
+// belong to the block that is being committed.
+func (p *EthereumParser) fetchTokenTransfers(ctx context.Context, header types.BlockHeader, addresses map[types.Address]bool) (map[types.Address][]types.TokenTransfer, error) {
+	matches := make(map[types.Address][]types.TokenTransfer)
+	if len(addresses) == 0 {
+		return matches, nil
+	}
+
+	topics := make([]string, 0, len(addresses))
+	for address := range addresses {
+		topics = append(topics, addressTopic(address))
+	}
+
+	// Transfer indexes from/to as topics 1 and 2, the ERC-1155 events as
+	// topics 2 and 3 (after the operator), which takes three queries
+	filters := [][][]string{
+		{{transferTopic}, topics},
+		{{transferTopic, transferSingleTopic, transferBatchTopic}, nil, topics},
+		{{transferSingleTopic, transferBatchTopic}, nil, nil, topics},
+	}
+
+	seen := make(map[string]bool)
+	for _, topicFilter := range filters {
+		logs, err := p.client.GetLogs(ctx, types.LogFilter{BlockHash: header.Hash, Topics: topicFilter})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transfer logs for block %d: %w", header.Number, err)
+		}
+
+		for _, l := range logs {
+			key := fmt.Sprintf("%s:%d", l.TransactionHash, l.LogIndex)
+			if l.Removed || seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			// Contracts may emit logs with these signatures that do not follow
+			// the standards; they are skipped rather than failing the block
+			transfers, err := decodeTokenTransfers(l)
+			if err != nil {
+				log.Printf("Skipping malformed transfer log %s: %v", key, err)
+				continue
+			}
+
+			for _, transfer := range transfers {
+				if addresses[transfer.From] {
+					matches[transfer.From] = append(matches[transfer.From], transfer)
+				}
+				if transfer.To != transfer.From && addresses[transfer.To] {
+					matches[transfer.To] = append(matches[transfer.To], transfer)
+				}
+			}
+		}
+	}
+
+	return matches, nil
+}
+
+// decodeTokenTransfers decodes a transfer log into one transfer, or several
+// for an ERC-1155 TransferBatch log
+func decodeTokenTransfers(l types.Log) ([]types.TokenTransfer, error) {
+	if len(l.Topics) == 0 {
+		return nil, fmt.Errorf("log has no topics")
+	}
+
+	base := types.TokenTransfer{
+		TransactionHash: l.TransactionHash,
+		LogIndex:        l.LogIndex,
+		BlockNumber:     l.BlockNumber,
+		BlockHash:       l.BlockHash,
+		Token:           l.Address,
+	}
+
+	words, err := dataWords(l.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case l.Topics[0] == transferTopic && len(l.Topics) == 3:
+		// ERC-20: Transfer(address indexed from, address indexed to, uint256 value)
+		if len(words) != 1 {
+			return nil, fmt.Errorf("expected 1 data word, got %d", len(words))
+		}
+		if err := decodeParties(&base, l.Topics[1], l.Topics[2]); err != nil {
+			return nil, err
+		}
+		base.Standard = types.StandardERC20
+		base.Amount = words[0]
+		return []types.TokenTransfer{base}, nil
+
+	case l.Topics[0] == transferTopic && len(l.Topics) == 4:
+		// ERC-721: Transfer(address indexed from, address indexed to, uint256 indexed tokenId)
+		if err := decodeParties(&base, l.Topics[1], l.Topics[2]); err != nil {
+			return nil, err
+		}
+		tokenID, ok := new(big.Int).SetString(strings.TrimPrefix(l.Topics[3], "0x"), 16)
+		if !ok {
+			return nil, fmt.Errorf("invalid token ID %q", l.Topics[3])
+		}
+		base.Standard = types.StandardERC721
+		base.TokenID = tokenID
+		base.Amount = big.NewInt(1)
+		return []types.TokenTransfer{base}, nil
+
+	case l.Topics[0] == transferSingleTopic && len(l.Topics) == 4:
+		// TransferSingle(operator, from, to indexed; uint256 id, uint256 value)
+		if len(words) != 2 {
+			return nil, fmt.Errorf("expected 2 data words, got %d", len(words))
+		}
+		if err := decodeERC1155Parties(&base, l.Topics); err != nil {
+			return nil, err
+		}
+		base.TokenID = words[0]
+		base.Amount = words[1]
+		return []types.TokenTransfer{base}, nil
+
+	case l.Topics[0] == transferBatchTopic && len(l.Topics) == 4:
+		// TransferBatch(operator, from, to indexed; uint256[] ids, uint256[] values)
+		if err := decodeERC1155Parties(&base, l.Topics); err != nil {
+			return nil, err
+		}
+		ids, err := decodeUintArray(words, 0)
+		if err != nil {
+			return nil, fmt.Errorf("ids: %v", err)
+		}
+		values, err := decodeUintArray(words, 1)
+		if err != nil {
+			return nil, fmt.Errorf("values: %v", err)
+		}
+		if len(ids) != len(values) {
+			return nil, fmt.Errorf("got %d ids and %d values", len(ids), len(values))
+		}
+
+		transfers := make([]types.TokenTransfer, 0, len(ids))
+		for i := range ids {
+			transfer := base
+			transfer.BatchIndex = i
+			transfer.TokenID = ids[i]
+			transfer.Amount = values[i]
+			transfers = append(transfers, transfer)
+		}
+		return transfers, nil
+	}
+
+	return nil, fmt.Errorf("unsupported transfer log with %d topics", len(l.Topics))
+}
+
+func decodeParties(transfer *types.TokenTransfer, fromTopic, toTopic string) error {
+	var err error
+	if transfer.From, err = topicAddress(fromTopic); err != nil {
+		return err
+	}
+	if transfer.To, err = topicAddress(toTopic); err != nil {
+		return err
+	}
+	return nil
+}
+
+func decodeERC1155Parties(transfer *types.TokenTransfer, topics []string) error {
+	var err error
+	if transfer.Operator, err = topicAddress(topics[1]); err != nil {
+		return err
+	}
+	transfer.Standard = types.StandardERC1155
+	return decodeParties(transfer, topics[2], topics[3])
+}
+
+// dataWords splits ABI-encoded log data into 32-byte words
+func dataWords(data string) ([]*big.Int, error) {
+	hex := strings.TrimPrefix(data, "0x")
+	if len(hex)%64 != 0 {
+		return nil, fmt.Errorf("log data is not a multiple of 32 bytes")
+	}
+
+	words := make([]*big.Int, 0, len(hex)/64)
+	for i := 0; i < len(hex); i += 64 {
+		word, ok := new(big.Int).SetString(hex[i:i+64], 16)
+		if !ok {
+			return nil, fmt.Errorf("invalid log data word at offset %d", i/2)
+		}
+		words = append(words, word)
+	}
+	return words, nil
+}
+
+// decodeUintArray decodes the dynamic uint256[] argument at position arg of
+// the ABI-encoded words
+func decodeUintArray(words []*big.Int, arg int) ([]*big.Int, error) {
+	if arg >= len(words) {
+		return nil, fmt.Errorf("missing offset")
+	}
+
+	offset := words[arg]
+	if !offset.IsInt64() || offset.Int64()%32 != 0 {
+		return nil, fmt.Errorf("invalid offset %s", offset)
+	}
+	start := int(offset.Int64() / 32)
+	if start >= len(words) {
+		return nil, fmt.Errorf("offset %s out of range", offset)
+	}
+
+	length := words[start]
+	if !length.IsInt64() || length.Int64() > int64(len(words)-start-1) {
+		return nil, fmt.Errorf("invalid length %s", length)
+	}
+
+	return words[start+1 : start+1+int(length.Int64())], nil
+}
+
+// storeTokenTransfers persists matched token transfers with the given status
+// and optionally sends webhook notifications for the new ones
+func (p *EthereumParser) storeTokenTransfers(matches map[types.Address][]types.TokenTransfer, status types.ConfirmationStatus, notify bool) error {
+	for address, transfers := range matches {
+		for _, transfer := range transfers {
+			transfer.Confirmation = status
+			stored, err := p.storage.StoreTokenTransfer(address, transfer)
+			if err != nil {
+				return fmt.Errorf("failed to store token transfer %s:%d for %s: %v",
+					transfer.TransactionHash, transfer.LogIndex, address, err)
+			}
+
+			if notify && stored {
+				p.notifyTokenTransfer(transfer, address, eventNewTokenTransfer, p.config.WebhookURL)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+// word encodes a value as a 32-byte ABI word without 0x prefix
+func word(v int) string {
+	return fmt.Sprintf("%064x", v)
+}
+
+func TestDecodeERC20Transfer(t *testing.T) {
+	from, to := testAddress(1), testAddress(2)
+
+	transfers, err := decodeTokenTransfers(types.Log{
+		Address:         testAddress(99),
+		Topics:          []string{transferTopic, addressTopic(from), addressTopic(to)},
+		Data:            "0x" + word(1000),
+		BlockNumber:     100,
+		TransactionHash: "0xabc",
+		LogIndex:        7,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(transfers) != 1 {
+		t.Fatalf("Expected 1 transfer, got %d", len(transfers))
+	}
+
+	transfer := transfers[0]
+	if transfer.Standard != types.StandardERC20 {
+		t.Errorf("Expected standard erc20, got %s", transfer.Standard)
+	}
+	if transfer.From != from || transfer.To != to || transfer.Token != testAddress(99) {
+		t.Errorf("Unexpected addresses: %+v", transfer)
+	}
+	if transfer.Amount.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("Expected amount 1000, got %s", transfer.Amount)
+	}
+	if transfer.LogIndex != 7 {
+		t.Errorf("Expected log index 7, got %d", transfer.LogIndex)
+	}
+}
+
+func TestDecodeERC721Transfer(t *testing.T) {
+	transfers, err := decodeTokenTransfers(types.Log{
+		Address: testAddress(99),
+		Topics:  []string{transferTopic, addressTopic(testAddress(1)), addressTopic(testAddress(2)), "0x" + word(42)},
+		Data:    "0x",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(transfers) != 1 {
+		t.Fatalf("Expected 1 transfer, got %d", len(transfers))
+	}
+
+	transfer := transfers[0]
+	if transfer.Standard != types.StandardERC721 || transfer.TokenID.Int64() != 42 || transfer.Amount.Int64() != 1 {
+		t.Errorf("Unexpected transfer: %+v", transfer)
+	}
+}
+
+func TestDecodeERC1155TransferBatch(t *testing.T) {
+	operator, from, to := testAddress(3), testAddress(1), testAddress(2)
+
+	// ids [7, 8] and values [10, 20] as two dynamic uint256[] arguments
+	data := "0x" + word(64) + word(160) +
+		word(2) + word(7) + word(8) +
+		word(2) + word(10) + word(20)
+
+	transfers, err := decodeTokenTransfers(types.Log{
+		Address:  testAddress(99),
+		Topics:   []string{transferBatchTopic, addressTopic(operator), addressTopic(from), addressTopic(to)},
+		Data:     data,
+		LogIndex: 3,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(transfers) != 2 {
+		t.Fatalf("Expected 2 transfers, got %d", len(transfers))
+	}
+
+	for i, transfer := range transfers {
+		if transfer.Standard != types.StandardERC1155 || transfer.Operator != operator || transfer.From != from || transfer.To != to {
+			t.Errorf("Unexpected transfer %d: %+v", i, transfer)
+		}
+		if transfer.BatchIndex != i || transfer.TokenID.Int64() != int64(7+i) || transfer.Amount.Int64() != int64(10*(i+1)) {
+			t.Errorf("Unexpected batch entry %d: index %d, id %s, amount %s", i, transfer.BatchIndex, transfer.TokenID, transfer.Amount)
+		}
+	}
+
+	// A length pointing past the end of the data must be rejected
+	_, err = decodeTokenTransfers(types.Log{
+		Topics: []string{transferBatchTopic, addressTopic(operator), addressTopic(from), addressTopic(to)},
+		Data:   "0x" + word(64) + word(96) + word(5),
+	})
+	if err == nil {
+		t.Errorf("Expected error for truncated batch")
+	}
+}
@@ -0,0 +1,621 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+const (
+	segmentExt            = ".seg"
+	recordHeaderSize      = 8 // 4-byte payload length + 4-byte CRC32
+	maxRecordSize         = 16 << 20
+	defaultMaxSegmentSize = 64 << 20
+)
+
+// Log operations
+const (
+	opStoreTransaction = "store_tx"
+	opSaveCheckpoint   = "checkpoint"
+	opRemoveFrom       = "remove_from"
+	opPromote          = "promote" // only replayed from older logs
+	opSaveBackfillJob  = "backfill_job"
+
+	opStoreTokenTransfer       = "store_token_transfer"
+	opRemoveTokenTransfersFrom = "remove_token_transfers_from"
+
+	opStoreInternalTransfer       = "store_internal_transfer"
+	opRemoveInternalTransfersFrom = "remove_internal_transfers_from"
+
+	opSavePendingTransaction = "pending_tx"
+
+	opSaveDisagreement = "disagreement"
+)
+
+var (
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+	errTornRecord = errors.New("torn record")
+)
+
+// logRecord is a single entry of the segment log
+type logRecord struct {
+	Op           string                    `json:"op"`
+	Address      types.Address             `json:"address,omitempty"`
+	Transaction  *types.Transaction        `json:"transaction,omitempty"`
+	Checkpoint   *types.Checkpoint         `json:"checkpoint,omitempty"`
+	BlockNumber  int64                     `json:"blockNumber,omitempty"`
+	Status       types.ConfirmationStatus  `json:"status,omitempty"`
+	BackfillJob  *types.BackfillJob        `json:"backfillJob,omitempty"`
+	Transfer     *types.TokenTransfer      `json:"transfer,omitempty"`
+	Internal     *types.InternalTransfer   `json:"internal,omitempty"`
+	Pending      *types.PendingTransaction `json:"pending,omitempty"`
+	Disagreement *types.Disagreement       `json:"disagreement,omitempty"`
+}
+
+// DiskStorage persists every write to an append-only log split into segment
+// files and serves reads from an in-memory index rebuilt from the log on
+// startup. Each write is fsynced before it becomes visible to readers.
+type DiskStorage struct {
+	dir            string
+	index          *MemoryStorage
+	maxSegmentSize int64
+	// lock holds the exclusive lock on dir while the storage is open
+	lock *os.File
+
+	mu         sync.Mutex
+	active     *os.File
+	activeID   int
+	activeSize int64
+	// logSize is the size of every segment, and compactedSize the size of
+	// the snapshot written by the last compaction
+	logSize       int64
+	compactedSize int64
+}
+
+// NewDiskStorage opens (or creates) a disk-backed storage in dir. A torn
+// record at the end of the newest segment, left by a crash mid-write, is
+// truncated away. dir is locked until Close, so a second process opening it
+// fails instead of interleaving its writes.
+func NewDiskStorage(dir string) (*DiskStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	lock, err := lockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ds := &DiskStorage{
+		dir:            dir,
+		index:          NewMemoryStorage(),
+		maxSegmentSize: defaultMaxSegmentSize,
+		lock:           lock,
+		activeID:       1,
+	}
+
+	if err := ds.open(); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return ds, nil
+}
+
+// open replays the segments and opens the newest one for appending
+func (ds *DiskStorage) open() error {
+	ids, err := listSegments(ds.dir)
+	if err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		last := i == len(ids)-1
+		size, err := ds.replaySegment(id, last)
+		if err != nil {
+			return err
+		}
+		ds.logSize += size
+		if last {
+			ds.activeID = id
+			ds.activeSize = size
+		}
+	}
+
+	return ds.openActiveSegment()
+}
+
+// StoreTransaction skips duplicates before they reach the log
+func (ds *DiskStorage) StoreTransaction(address types.Address, tx types.Transaction) (bool, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if ds.index.hasTransaction(address, tx) {
+		return false, nil
+	}
+	if err := ds.append(logRecord{Op: opStoreTransaction, Address: address, Transaction: &tx}); err != nil {
+		return false, err
+	}
+	return ds.index.StoreTransaction(address, tx)
+}
+
+func (ds *DiskStorage) GetTransactions(address types.Address) ([]types.Transaction, error) {
+	return ds.index.GetTransactions(address)
+}
+
+func (ds *DiskStorage) RemoveTransactionsFrom(blockNumber int64) (map[types.Address][]types.Transaction, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if err := ds.append(logRecord{Op: opRemoveFrom, BlockNumber: blockNumber}); err != nil {
+		return nil, err
+	}
+	return ds.index.RemoveTransactionsFrom(blockNumber)
+}
+
+// StoreTokenTransfer skips duplicates before they reach the log
+func (ds *DiskStorage) StoreTokenTransfer(address types.Address, transfer types.TokenTransfer) (bool, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if ds.index.hasTokenTransfer(address, transfer) {
+		return false, nil
+	}
+	if err := ds.append(logRecord{Op: opStoreTokenTransfer, Address: address, Transfer: &transfer}); err != nil {
+		return false, err
+	}
+	return ds.index.StoreTokenTransfer(address, transfer)
+}
+
+func (ds *DiskStorage) GetTokenTransfers(address types.Address) ([]types.TokenTransfer, error) {
+	return ds.index.GetTokenTransfers(address)
+}
+
+func (ds *DiskStorage) RemoveTokenTransfersFrom(blockNumber int64) (map[types.Address][]types.TokenTransfer, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if err := ds.append(logRecord{Op: opRemoveTokenTransfersFrom, BlockNumber: blockNumber}); err != nil {
+		return nil, err
+	}
+	return ds.index.RemoveTokenTransfersFrom(blockNumber)
+}
+
+// StoreInternalTransfer skips duplicates before they reach the log
+func (ds *DiskStorage) StoreInternalTransfer(address types.Address, transfer types.InternalTransfer) (bool, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if ds.index.hasInternalTransfer(address, transfer) {
+		return false, nil
+	}
+	if err := ds.append(logRecord{Op: opStoreInternalTransfer, Address: address, Internal: &transfer}); err != nil {
+		return false, err
+	}
+	return ds.index.StoreInternalTransfer(address, transfer)
+}
+
+func (ds *DiskStorage) GetInternalTransfers(address types.Address) ([]types.InternalTransfer, error) {
+	return ds.index.GetInternalTransfers(address)
+}
+
+func (ds *DiskStorage) RemoveInternalTransfersFrom(blockNumber int64) (map[types.Address][]types.InternalTransfer, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if err := ds.append(logRecord{Op: opRemoveInternalTransfersFrom, BlockNumber: blockNumber}); err != nil {
+		return nil, err
+	}
+	return ds.index.RemoveInternalTransfersFrom(blockNumber)
+}
+
+func (ds *DiskStorage) SavePendingTransaction(address types.Address, tx types.PendingTransaction) error {
+	return ds.commit(logRecord{Op: opSavePendingTransaction, Address: address, Pending: &tx})
+}
+
+func (ds *DiskStorage) GetPendingTransactions(address types.Address) ([]types.PendingTransaction, error) {
+	return ds.index.GetPendingTransactions(address)
+}
+
+func (ds *DiskStorage) GetPendingTransactionsByStatus(status types.PendingStatus) (map[types.Address][]types.PendingTransaction, error) {
+	return ds.index.GetPendingTransactionsByStatus(status)
+}
+
+func (ds *DiskStorage) SaveDisagreement(d types.Disagreement) error {
+	return ds.commit(logRecord{Op: opSaveDisagreement, Disagreement: &d})
+}
+
+func (ds *DiskStorage) GetDisagreements() ([]types.Disagreement, error) {
+	return ds.index.GetDisagreements()
+}
+
+func (ds *DiskStorage) SaveCheckpoint(cp types.Checkpoint) error {
+	return ds.commit(logRecord{Op: opSaveCheckpoint, Checkpoint: &cp})
+}
+
+func (ds *DiskStorage) GetCheckpoint() (*types.Checkpoint, error) {
+	return ds.index.GetCheckpoint()
+}
+
+func (ds *DiskStorage) GetCheckpoints() ([]types.Checkpoint, error) {
+	return ds.index.GetCheckpoints()
+}
+
+func (ds *DiskStorage) SaveBackfillJob(job types.BackfillJob) error {
+	return ds.commit(logRecord{Op: opSaveBackfillJob, BackfillJob: &job})
+}
+
+func (ds *DiskStorage) GetBackfillJob(id string) (*types.BackfillJob, error) {
+	return ds.index.GetBackfillJob(id)
+}
+
+func (ds *DiskStorage) GetBackfillJobs() ([]types.BackfillJob, error) {
+	return ds.index.GetBackfillJobs()
+}
+
+// Close closes the active segment file and releases the data directory
+func (ds *DiskStorage) Close() error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if ds.active == nil {
+		return nil
+	}
+	err := ds.active.Close()
+	ds.active = nil
+	ds.lock.Close()
+	return err
+}
+
+// commit appends rec to the log, fsyncs it and then applies it to the index
+func (ds *DiskStorage) commit(rec logRecord) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if err := ds.append(rec); err != nil {
+		return err
+	}
+	return ds.apply(rec)
+}
+
+// append writes rec to the active segment and fsyncs it. The caller must
+// hold ds.mu.
+func (ds *DiskStorage) append(rec logRecord) error {
+	buf, err := encodeRecord(rec)
+	if err != nil {
+		return err
+	}
+
+	if ds.active == nil {
+		return fmt.Errorf("storage is closed")
+	}
+
+	if ds.activeSize > 0 && ds.activeSize+int64(len(buf)) > ds.maxSegmentSize {
+		if err := ds.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := ds.active.Write(buf); err != nil {
+		// Drop whatever part of the record made it to the file
+		ds.active.Truncate(ds.activeSize)
+		return fmt.Errorf("failed to write log record: %v", err)
+	}
+	if err := ds.active.Sync(); err != nil {
+		ds.active.Truncate(ds.activeSize)
+		return fmt.Errorf("failed to sync segment: %v", err)
+	}
+	ds.activeSize += int64(len(buf))
+	ds.logSize += int64(len(buf))
+
+	return nil
+}
+
+// apply updates the in-memory index with a committed record
+func (ds *DiskStorage) apply(rec logRecord) error {
+	switch rec.Op {
+	case opStoreTransaction:
+		if rec.Transaction == nil {
+			return fmt.Errorf("log record %q has no transaction", rec.Op)
+		}
+		_, err := ds.index.StoreTransaction(rec.Address, *rec.Transaction)
+		return err
+	case opSaveCheckpoint:
+		if rec.Checkpoint == nil {
+			return fmt.Errorf("log record %q has no checkpoint", rec.Op)
+		}
+		return ds.index.SaveCheckpoint(*rec.Checkpoint)
+	case opRemoveFrom:
+		_, err := ds.index.RemoveTransactionsFrom(rec.BlockNumber)
+		return err
+	case opPromote:
+		return ds.index.promoteConfirmations(rec.Status, rec.BlockNumber)
+	case opStoreTokenTransfer:
+		if rec.Transfer == nil {
+			return fmt.Errorf("log record %q has no token transfer", rec.Op)
+		}
+		_, err := ds.index.StoreTokenTransfer(rec.Address, *rec.Transfer)
+		return err
+	case opRemoveTokenTransfersFrom:
+		_, err := ds.index.RemoveTokenTransfersFrom(rec.BlockNumber)
+		return err
+	case opStoreInternalTransfer:
+		if rec.Internal == nil {
+			return fmt.Errorf("log record %q has no internal transfer", rec.Op)
+		}
+		_, err := ds.index.StoreInternalTransfer(rec.Address, *rec.Internal)
+		return err
+	case opRemoveInternalTransfersFrom:
+		_, err := ds.index.RemoveInternalTransfersFrom(rec.BlockNumber)
+		return err
+	case opSavePendingTransaction:
+		if rec.Pending == nil {
+			return fmt.Errorf("log record %q has no pending transaction", rec.Op)
+		}
+		return ds.index.SavePendingTransaction(rec.Address, *rec.Pending)
+	case opSaveDisagreement:
+		if rec.Disagreement == nil {
+			return fmt.Errorf("log record %q has no disagreement", rec.Op)
+		}
+		return ds.index.SaveDisagreement(*rec.Disagreement)
+	case opSaveBackfillJob:
+		if rec.BackfillJob == nil {
+			return fmt.Errorf("log record %q has no backfill job", rec.Op)
+		}
+		return ds.index.SaveBackfillJob(*rec.BackfillJob)
+	default:
+		return fmt.Errorf("unknown log operation %q", rec.Op)
+	}
+}
+
+// replaySegment applies every record of a segment to the index and returns
+// the size of its valid prefix. Only the newest segment may end in a torn
+// record; it is truncated so that new writes start on a record boundary.
+func (ds *DiskStorage) replaySegment(id int, last bool) (int64, error) {
+	path := ds.segmentPath(id)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read segment %s: %v", path, err)
+	}
+
+	var offset int64
+	for offset < int64(len(data)) {
+		rec, n, err := decodeRecord(data[offset:])
+		if err != nil {
+			if !last {
+				return 0, fmt.Errorf("corrupt record in segment %s at offset %d: %v", path, offset, err)
+			}
+			log.Printf("Recovering segment %s: truncating %d bytes at offset %d (%v)",
+				path, int64(len(data))-offset, offset, err)
+			if err := truncateFile(path, offset); err != nil {
+				return 0, err
+			}
+			return offset, nil
+		}
+
+		if err := ds.apply(rec); err != nil {
+			return 0, fmt.Errorf("failed to replay segment %s at offset %d: %v", path, offset, err)
+		}
+		offset += int64(n)
+	}
+
+	return offset, nil
+}
+
+// rotate seals the active segment and starts a new one, compacting the log
+// first once it has grown to twice the size of the state it holds
+func (ds *DiskStorage) rotate() error {
+	if err := ds.active.Close(); err != nil {
+		return fmt.Errorf("failed to close segment: %v", err)
+	}
+	ds.active = nil
+
+	if ds.logSize > 2*max(ds.compactedSize, ds.maxSegmentSize) {
+		if err := ds.compact(); err != nil {
+			return err
+		}
+	}
+
+	ds.activeID++
+	ds.activeSize = 0
+	return ds.openActiveSegment()
+}
+
+// compact writes the current state as a snapshot segment after the active
+// one and deletes every segment before it. Replaying the snapshot on top of
+// segments left by a crash mid-compaction yields the same state, so the
+// deletions need no coordination. The caller must hold ds.mu.
+func (ds *DiskStorage) compact() error {
+	tmp := filepath.Join(ds.dir, "snapshot.tmp")
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %v", err)
+	}
+
+	w := bufio.NewWriter(f)
+	var size int64
+	for _, rec := range ds.index.snapshot() {
+		buf, err := encodeRecord(rec)
+		if err == nil {
+			_, err = w.Write(buf)
+		}
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write snapshot: %v", err)
+		}
+		size += int64(len(buf))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write snapshot: %v", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync snapshot: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot: %v", err)
+	}
+
+	snapshotID := ds.activeID + 1
+	if err := os.Rename(tmp, ds.segmentPath(snapshotID)); err != nil {
+		return fmt.Errorf("failed to install snapshot: %v", err)
+	}
+	if err := syncDir(ds.dir); err != nil {
+		return err
+	}
+
+	ids, err := listSegments(ds.dir)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if id < snapshotID {
+			if err := os.Remove(ds.segmentPath(id)); err != nil {
+				return fmt.Errorf("failed to remove compacted segment: %v", err)
+			}
+		}
+	}
+
+	log.Printf("Compacted storage log from %d to %d bytes", ds.logSize, size)
+	ds.activeID = snapshotID
+	ds.logSize = size
+	ds.compactedSize = size
+	return syncDir(ds.dir)
+}
+
+func (ds *DiskStorage) openActiveSegment() error {
+	path := ds.segmentPath(ds.activeID)
+
+	_, statErr := os.Stat(path)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open segment %s: %v", path, err)
+	}
+
+	// Make a newly created segment durable in the directory listing
+	if os.IsNotExist(statErr) {
+		if err := syncDir(ds.dir); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	ds.active = f
+	return nil
+}
+
+func (ds *DiskStorage) segmentPath(id int) string {
+	return filepath.Join(ds.dir, fmt.Sprintf("%08d%s", id, segmentExt))
+}
+
+// encodeRecord frames rec with its length and checksum
+func encodeRecord(rec logRecord) ([]byte, error) {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal log record: %v", err)
+	}
+	if len(payload) > maxRecordSize {
+		return nil, fmt.Errorf("log record too large: %d bytes", len(payload))
+	}
+
+	buf := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(payload, crcTable))
+	copy(buf[recordHeaderSize:], payload)
+	return buf, nil
+}
+
+// decodeRecord parses one record from buf and returns it with its encoded size
+func decodeRecord(buf []byte) (logRecord, int, error) {
+	var rec logRecord
+
+	if len(buf) < recordHeaderSize {
+		return rec, 0, errTornRecord
+	}
+
+	length := binary.BigEndian.Uint32(buf[0:4])
+	checksum := binary.BigEndian.Uint32(buf[4:8])
+	if length > maxRecordSize {
+		return rec, 0, fmt.Errorf("record length %d exceeds limit", length)
+	}
+
+	end := recordHeaderSize + int(length)
+	if len(buf) < end {
+		return rec, 0, errTornRecord
+	}
+
+	payload := buf[recordHeaderSize:end]
+	if crc32.Checksum(payload, crcTable) != checksum {
+		return rec, 0, fmt.Errorf("checksum mismatch")
+	}
+
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return rec, 0, fmt.Errorf("failed to unmarshal record: %v", err)
+	}
+
+	return rec, end, nil
+}
+
+// listSegments returns the IDs of the segment files in dir in ascending order
+func listSegments(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data directory: %v", err)
+	}
+
+	var ids []int
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSuffix(name, segmentExt))
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+	return ids, nil
+}
+
+func truncateFile(path string, size int64) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open segment %s: %v", path, err)
+	}
+	defer f.Close()
+
+	if err := f.Truncate(size); err != nil {
+		return fmt.Errorf("failed to truncate segment %s: %v", path, err)
+	}
+	return f.Sync()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open data directory: %v", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync data directory: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum_parser/internal/types"
+)
+
+func TestDiskStorage(t *testing.T) {
+	dir := t.TempDir()
+
+	storage, err := NewDiskStorage(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	testTx := types.Transaction{
+		Hash:        "0x123",
+		From:        "0xSender",
+		To:          "0xReceiver",
+		Value:       big.NewInt(1000),
+		BlockNumber: 100,
+	}
+	if _, err := storage.StoreTransaction("0xSender", testTx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Reopen and make sure the transaction survived
+	storage, err = NewDiskStorage(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer storage.Close()
+
+	txs, err := storage.GetTransactions("0xSender")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(txs) != 1 {
+		t.Fatalf("Expected 1 transaction, got %d", len(txs))
+	}
+
+	if txs[0].Hash != "0x123" || txs[0].Value.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("Unexpected transaction: %+v", txs[0])
+	}
+}
+
+func TestDiskStorageRecoversTornRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	storage, err := NewDiskStorage(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	storage.StoreTransaction("0xSender", types.Transaction{Hash: "0x123", Value: big.NewInt(1)})
+	storage.Close()
+
+	// Simulate a crash in the middle of writing the next record
+	segment := storage.segmentPath(1)
+	f, err := os.OpenFile(segment, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	f.Write([]byte{0, 0, 1, 0, 0xde, 0xad, '{', '"'})
+	f.Close()
+
+	storage, err = NewDiskStorage(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := storage.StoreTransaction("0xSender", types.Transaction{Hash: "0x456", Value: big.NewInt(2)}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	storage.Close()
+
+	storage, err = NewDiskStorage(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer storage.Close()
+
+	txs, _ := storage.GetTransactions("0xSender")
+	if len(txs) != 2 {
+		t.Fatalf("Expected 2 transactions, got %d", len(txs))
+	}
+
+	if txs[0].Hash != "0x123" || txs[1].Hash != "0x456" {
+		t.Errorf("Unexpected transactions: %s, %s", txs[0].Hash, txs[1].Hash)
+	}
+}
+
+func TestDiskStorageLocksDataDir(t *testing.T) {
+	dir := t.TempDir()
+
+	storage, err := NewDiskStorage(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := NewDiskStorage(dir); err == nil {
+		t.Fatalf("Expected a second writer to be rejected")
+	}
+
+	storage.Close()
+	storage, err = NewDiskStorage(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error after close: %v", err)
+	}
+	storage.Close()
+}
+
+func TestDiskStorageKeepsCheckpointHistory(t *testing.T) {
+	dir := t.TempDir()
+
+	storage, err := NewDiskStorage(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, n := range []int64{10, 11, 12, 13, 11, 12} {
+		if err := storage.SaveCheckpoint(types.Checkpoint{BlockNumber: n, BlockHash: fmt.Sprintf("0x%d", n)}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	storage, err = NewDiskStorage(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer storage.Close()
+
+	// The rollback to block 11 discards the checkpoints saved above it
+	checkpoints, err := storage.GetCheckpoints()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var numbers []int64
+	for _, cp := range checkpoints {
+		numbers = append(numbers, cp.BlockNumber)
+	}
+	if fmt.Sprint(numbers) != "[10 11 12]" {
+		t.Errorf("Expected checkpoints [10 11 12], got %v", numbers)
+	}
+
+	checkpoint, err := storage.GetCheckpoint()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if checkpoint == nil || checkpoint.BlockNumber != 12 {
+		t.Errorf("Expected checkpoint at block 12, got %+v", checkpoint)
+	}
+}
+
+func TestDiskStorageCompactsLog(t *testing.T) {
+	dir := t.TempDir()
+
+	storage, err := NewDiskStorage(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	storage.maxSegmentSize = 4 << 10
+
+	tx := types.Transaction{Hash: "0x123", From: "0xSender", Value: big.NewInt(1000), BlockNumber: 100}
+	if _, err := storage.StoreTransaction("0xSender", tx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Rewriting the same pending transaction grows the log but not the state
+	for i := 0; i < 1000; i++ {
+		pending := types.PendingTransaction{Transaction: types.Transaction{Hash: "0xabc", Nonce: uint64(i)}, Status: types.PendingStatusPending}
+		if err := storage.SavePendingTransaction("0xSender", pending); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ids, err := listSegments(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ids) > 4 {
+		t.Errorf("Expected the log to be compacted, found %d segments", len(ids))
+	}
+
+	storage, err = NewDiskStorage(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer storage.Close()
+
+	txs, _ := storage.GetTransactions("0xSender")
+	if len(txs) != 1 || txs[0].Value.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("Unexpected transactions after compaction: %+v", txs)
+	}
+	pending, _ := storage.GetPendingTransactions("0xSender")
+	if len(pending) != 1 || pending[0].Transaction.Nonce != 999 {
+		t.Errorf("Unexpected pending transactions after compaction: %+v", pending)
+	}
+}
@@ -1,38 +1,513 @@
 package storage
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/ethereum_parser/internal/types"
 )
 
+// checkpointHistory is the number of recent checkpoints kept, so the blocks
+// tracked for reorg detection survive a restart
+const checkpointHistory = 1024
+
 // Storage defines the interface for transaction storage
 type Storage interface {
-	StoreTransaction(address string, tx types.Transaction)
-	GetTransactions(address string) ([]types.Transaction, error)
+	// StoreTransaction is idempotent: a transaction already stored for the
+	// address is ignored. It reports whether tx was new.
+	StoreTransaction(address types.Address, tx types.Transaction) (bool, error)
+	GetTransactions(address types.Address) ([]types.Transaction, error)
+	// RemoveTransactionsFrom deletes every transaction included in
+	// blockNumber or later and returns them keyed by address
+	RemoveTransactionsFrom(blockNumber int64) (map[types.Address][]types.Transaction, error)
+	// StoreTokenTransfer is idempotent: a transfer already stored for the
+	// address is ignored. It reports whether transfer was new.
+	StoreTokenTransfer(address types.Address, transfer types.TokenTransfer) (bool, error)
+	GetTokenTransfers(address types.Address) ([]types.TokenTransfer, error)
+	// RemoveTokenTransfersFrom deletes every token transfer included in
+	// blockNumber or later and returns them keyed by address
+	RemoveTokenTransfersFrom(blockNumber int64) (map[types.Address][]types.TokenTransfer, error)
+	// StoreInternalTransfer is idempotent: a transfer already stored for the
+	// address is ignored. It reports whether transfer was new.
+	StoreInternalTransfer(address types.Address, transfer types.InternalTransfer) (bool, error)
+	GetInternalTransfers(address types.Address) ([]types.InternalTransfer, error)
+	// RemoveInternalTransfersFrom deletes every internal transfer included
+	// in blockNumber or later and returns them keyed by address
+	RemoveInternalTransfersFrom(blockNumber int64) (map[types.Address][]types.InternalTransfer, error)
+	// SavePendingTransaction stores a mempool transaction for the address,
+	// replacing the entry with the same hash
+	SavePendingTransaction(address types.Address, tx types.PendingTransaction) error
+	GetPendingTransactions(address types.Address) ([]types.PendingTransaction, error)
+	// GetPendingTransactionsByStatus returns every mempool transaction with
+	// the given status, keyed by address
+	GetPendingTransactionsByStatus(status types.PendingStatus) (map[types.Address][]types.PendingTransaction, error)
+	// SaveDisagreement stores a provider disagreement, replacing the entry
+	// for the same block and subject but keeping its FirstSeen
+	SaveDisagreement(d types.Disagreement) error
+	GetDisagreements() ([]types.Disagreement, error)
+	// SaveCheckpoint records cp as the last processed block, discarding the
+	// checkpoints at or above it saved before a rollback. Transactions and
+	// transfers are read with at least the confirmation status implied by
+	// the watermarks of the last checkpoint.
+	SaveCheckpoint(cp types.Checkpoint) error
+	// GetCheckpoint returns nil if no checkpoint has been saved yet
+	GetCheckpoint() (*types.Checkpoint, error)
+	// GetCheckpoints returns up to the last checkpointHistory checkpoints,
+	// oldest first
+	GetCheckpoints() ([]types.Checkpoint, error)
+	SaveBackfillJob(job types.BackfillJob) error
+	// GetBackfillJob returns nil if no job with the given ID exists
+	GetBackfillJob(id string) (*types.BackfillJob, error)
+	GetBackfillJobs() ([]types.BackfillJob, error)
+	Close() error
 }
 
 type MemoryStorage struct {
-	transactions map[string][]types.Transaction
+	transactions map[types.Address][]types.Transaction
+	// hashes indexes the stored transaction hashes per address
+	hashes    map[types.Address]map[string]bool
+	transfers map[types.Address][]types.TokenTransfer
+	// transferKeys indexes the stored transfers per address by tx hash, log
+	// index and batch index
+	transferKeys      map[types.Address]map[string]bool
+	internalTransfers map[types.Address][]types.InternalTransfer
+	// internalKeys indexes the stored internal transfers per address by tx
+	// hash and trace index
+	internalKeys map[types.Address]map[string]bool
+	pending      map[types.Address][]types.PendingTransaction
+	// pendingIndex locates the pending transactions per address by hash
+	pendingIndex  map[types.Address]map[string]int
+	disagreements []types.Disagreement
+	// disagreementIndex locates the disagreements by block and subject
+	disagreementIndex map[string]int
+	// checkpoints are the most recent checkpoints, oldest first
+	checkpoints  []types.Checkpoint
+	backfillJobs map[string]types.BackfillJob
+	jobOrder     []string
 	mu           sync.RWMutex
 }
 
 func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
-		transactions: make(map[string][]types.Transaction),
+		transactions:      make(map[types.Address][]types.Transaction),
+		hashes:            make(map[types.Address]map[string]bool),
+		transfers:         make(map[types.Address][]types.TokenTransfer),
+		transferKeys:      make(map[types.Address]map[string]bool),
+		internalTransfers: make(map[types.Address][]types.InternalTransfer),
+		internalKeys:      make(map[types.Address]map[string]bool),
+		pending:           make(map[types.Address][]types.PendingTransaction),
+		pendingIndex:      make(map[types.Address]map[string]int),
+		disagreementIndex: make(map[string]int),
+		backfillJobs:      make(map[string]types.BackfillJob),
 	}
 }
 
-func (ms *MemoryStorage) StoreTransaction(address string, tx types.Transaction) {
+func (ms *MemoryStorage) StoreTransaction(address types.Address, tx types.Transaction) (bool, error) {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
+	if ms.hashes[address][tx.Hash] {
+		return false, nil
+	}
+	if ms.hashes[address] == nil {
+		ms.hashes[address] = make(map[string]bool)
+	}
+	ms.hashes[address][tx.Hash] = true
+
 	ms.transactions[address] = append(ms.transactions[address], tx)
+	return true, nil
+}
+
+// hasTransaction reports whether tx is stored for address
+func (ms *MemoryStorage) hasTransaction(address types.Address, tx types.Transaction) bool {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	return ms.hashes[address][tx.Hash]
+}
+
+func (ms *MemoryStorage) GetTransactions(address types.Address) ([]types.Transaction, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	txs := append([]types.Transaction(nil), ms.transactions[address]...)
+	for i := range txs {
+		txs[i].Confirmation = ms.confirmation(txs[i].BlockNumber, txs[i].Confirmation)
+	}
+	return txs, nil
+}
+
+func (ms *MemoryStorage) RemoveTransactionsFrom(blockNumber int64) (map[types.Address][]types.Transaction, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	removed := make(map[types.Address][]types.Transaction)
+	for address, txs := range ms.transactions {
+		var kept []types.Transaction
+		for _, tx := range txs {
+			if tx.BlockNumber >= blockNumber {
+				removed[address] = append(removed[address], tx)
+				delete(ms.hashes[address], tx.Hash)
+				continue
+			}
+			kept = append(kept, tx)
+		}
+		ms.transactions[address] = kept
+	}
+
+	return removed, nil
+}
+
+func (ms *MemoryStorage) StoreTokenTransfer(address types.Address, transfer types.TokenTransfer) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	key := transferKey(transfer)
+	if ms.transferKeys[address][key] {
+		return false, nil
+	}
+	if ms.transferKeys[address] == nil {
+		ms.transferKeys[address] = make(map[string]bool)
+	}
+	ms.transferKeys[address][key] = true
+
+	ms.transfers[address] = append(ms.transfers[address], transfer)
+	return true, nil
+}
+
+// hasTokenTransfer reports whether transfer is stored for address
+func (ms *MemoryStorage) hasTokenTransfer(address types.Address, transfer types.TokenTransfer) bool {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	return ms.transferKeys[address][transferKey(transfer)]
+}
+
+func (ms *MemoryStorage) GetTokenTransfers(address types.Address) ([]types.TokenTransfer, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	transfers := append([]types.TokenTransfer(nil), ms.transfers[address]...)
+	for i := range transfers {
+		transfers[i].Confirmation = ms.confirmation(transfers[i].BlockNumber, transfers[i].Confirmation)
+	}
+	return transfers, nil
+}
+
+func (ms *MemoryStorage) RemoveTokenTransfersFrom(blockNumber int64) (map[types.Address][]types.TokenTransfer, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	removed := make(map[types.Address][]types.TokenTransfer)
+	for address, transfers := range ms.transfers {
+		var kept []types.TokenTransfer
+		for _, transfer := range transfers {
+			if transfer.BlockNumber >= blockNumber {
+				removed[address] = append(removed[address], transfer)
+				delete(ms.transferKeys[address], transferKey(transfer))
+				continue
+			}
+			kept = append(kept, transfer)
+		}
+		ms.transfers[address] = kept
+	}
+
+	return removed, nil
+}
+
+func (ms *MemoryStorage) StoreInternalTransfer(address types.Address, transfer types.InternalTransfer) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	key := internalKey(transfer)
+	if ms.internalKeys[address][key] {
+		return false, nil
+	}
+	if ms.internalKeys[address] == nil {
+		ms.internalKeys[address] = make(map[string]bool)
+	}
+	ms.internalKeys[address][key] = true
+
+	ms.internalTransfers[address] = append(ms.internalTransfers[address], transfer)
+	return true, nil
+}
+
+// hasInternalTransfer reports whether transfer is stored for address
+func (ms *MemoryStorage) hasInternalTransfer(address types.Address, transfer types.InternalTransfer) bool {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	return ms.internalKeys[address][internalKey(transfer)]
+}
+
+func (ms *MemoryStorage) GetInternalTransfers(address types.Address) ([]types.InternalTransfer, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	transfers := append([]types.InternalTransfer(nil), ms.internalTransfers[address]...)
+	for i := range transfers {
+		transfers[i].Confirmation = ms.confirmation(transfers[i].BlockNumber, transfers[i].Confirmation)
+	}
+	return transfers, nil
+}
+
+func (ms *MemoryStorage) RemoveInternalTransfersFrom(blockNumber int64) (map[types.Address][]types.InternalTransfer, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	removed := make(map[types.Address][]types.InternalTransfer)
+	for address, transfers := range ms.internalTransfers {
+		var kept []types.InternalTransfer
+		for _, transfer := range transfers {
+			if transfer.BlockNumber >= blockNumber {
+				removed[address] = append(removed[address], transfer)
+				delete(ms.internalKeys[address], internalKey(transfer))
+				continue
+			}
+			kept = append(kept, transfer)
+		}
+		ms.internalTransfers[address] = kept
+	}
+
+	return removed, nil
+}
+
+func (ms *MemoryStorage) SavePendingTransaction(address types.Address, tx types.PendingTransaction) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if i, exists := ms.pendingIndex[address][tx.Transaction.Hash]; exists {
+		ms.pending[address][i] = tx
+		return nil
+	}
+	if ms.pendingIndex[address] == nil {
+		ms.pendingIndex[address] = make(map[string]int)
+	}
+	ms.pendingIndex[address][tx.Transaction.Hash] = len(ms.pending[address])
+
+	ms.pending[address] = append(ms.pending[address], tx)
+	return nil
+}
+
+func (ms *MemoryStorage) GetPendingTransactions(address types.Address) ([]types.PendingTransaction, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	return append([]types.PendingTransaction(nil), ms.pending[address]...), nil
+}
+
+func (ms *MemoryStorage) GetPendingTransactionsByStatus(status types.PendingStatus) (map[types.Address][]types.PendingTransaction, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	matches := make(map[types.Address][]types.PendingTransaction)
+	for address, txs := range ms.pending {
+		for _, tx := range txs {
+			if tx.Status == status {
+				matches[address] = append(matches[address], tx)
+			}
+		}
+	}
+	return matches, nil
+}
+
+func disagreementKey(d types.Disagreement) string {
+	return fmt.Sprintf("%d:%s", d.BlockNumber, d.Subject)
+}
+
+func (ms *MemoryStorage) SaveDisagreement(d types.Disagreement) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	key := disagreementKey(d)
+	if i, ok := ms.disagreementIndex[key]; ok {
+		d.FirstSeen = ms.disagreements[i].FirstSeen
+		ms.disagreements[i] = d
+		return nil
+	}
+	ms.disagreementIndex[key] = len(ms.disagreements)
+	ms.disagreements = append(ms.disagreements, d)
+	return nil
+}
+
+func (ms *MemoryStorage) GetDisagreements() ([]types.Disagreement, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	return append([]types.Disagreement(nil), ms.disagreements...), nil
+}
+
+// promoteConfirmations applies the promote records of logs written before
+// the confirmation watermarks were kept in the checkpoint
+func (ms *MemoryStorage) promoteConfirmations(status types.ConfirmationStatus, throughBlock int64) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	// Getters return copies, so the stored slices can be updated in place
+
+	for _, txs := range ms.transactions {
+		for i := range txs {
+			if txs[i].BlockNumber <= throughBlock && !txs[i].Confirmation.AtLeast(status) {
+				txs[i].Confirmation = status
+			}
+		}
+	}
+
+	for _, transfers := range ms.transfers {
+		for i := range transfers {
+			if transfers[i].BlockNumber <= throughBlock && !transfers[i].Confirmation.AtLeast(status) {
+				transfers[i].Confirmation = status
+			}
+		}
+	}
+
+	for _, transfers := range ms.internalTransfers {
+		for i := range transfers {
+			if transfers[i].BlockNumber <= throughBlock && !transfers[i].Confirmation.AtLeast(status) {
+				transfers[i].Confirmation = status
+			}
+		}
+	}
+
+	return nil
+}
+
+// confirmation returns status raised to what the watermarks of the last
+// checkpoint imply for a record in blockNumber
+func (ms *MemoryStorage) confirmation(blockNumber int64, status types.ConfirmationStatus) types.ConfirmationStatus {
+	if len(ms.checkpoints) == 0 {
+		return status
+	}
+
+	cp := ms.checkpoints[len(ms.checkpoints)-1]
+	switch {
+	case blockNumber <= cp.FinalizedThrough:
+		return types.StatusFinalized
+	case blockNumber <= cp.ConfirmedThrough && !status.AtLeast(types.StatusConfirmed):
+		return types.StatusConfirmed
+	}
+	return status
+}
+
+func (ms *MemoryStorage) SaveCheckpoint(cp types.Checkpoint) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	n := len(ms.checkpoints)
+	for n > 0 && ms.checkpoints[n-1].BlockNumber >= cp.BlockNumber {
+		n--
+	}
+	ms.checkpoints = append(ms.checkpoints[:n], cp)
+	if len(ms.checkpoints) > checkpointHistory {
+		ms.checkpoints = append([]types.Checkpoint(nil), ms.checkpoints[len(ms.checkpoints)-checkpointHistory:]...)
+	}
+	return nil
+}
+
+func (ms *MemoryStorage) GetCheckpoint() (*types.Checkpoint, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	if len(ms.checkpoints) == 0 {
+		return nil, nil
+	}
+	cp := ms.checkpoints[len(ms.checkpoints)-1]
+	return &cp, nil
+}
+
+func (ms *MemoryStorage) GetCheckpoints() ([]types.Checkpoint, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	return append([]types.Checkpoint(nil), ms.checkpoints...), nil
 }
 
-func (ms *MemoryStorage) GetTransactions(address string) ([]types.Transaction, error) {
+func (ms *MemoryStorage) SaveBackfillJob(job types.BackfillJob) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, exists := ms.backfillJobs[job.ID]; !exists {
+		ms.jobOrder = append(ms.jobOrder, job.ID)
+	}
+	ms.backfillJobs[job.ID] = job
+	return nil
+}
+
+func (ms *MemoryStorage) GetBackfillJob(id string) (*types.BackfillJob, error) {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 
-	return ms.transactions[address], nil
+	job, exists := ms.backfillJobs[id]
+	if !exists {
+		return nil, nil
+	}
+	return &job, nil
+}
+
+func (ms *MemoryStorage) GetBackfillJobs() ([]types.BackfillJob, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	jobs := make([]types.BackfillJob, 0, len(ms.jobOrder))
+	for _, id := range ms.jobOrder {
+		jobs = append(jobs, ms.backfillJobs[id])
+	}
+	return jobs, nil
+}
+
+// Close is a no-op for in-memory storage
+func (ms *MemoryStorage) Close() error {
+	return nil
+}
+
+// transferKey identifies a token transfer by its transaction, log index and
+// position within a batch
+func transferKey(transfer types.TokenTransfer) string {
+	return fmt.Sprintf("%s:%d:%d", transfer.TransactionHash, transfer.LogIndex, transfer.BatchIndex)
+}
+
+// internalKey identifies an internal transfer by its transaction and trace
+// index
+func internalKey(transfer types.InternalTransfer) string {
+	return fmt.Sprintf("%s:%d", transfer.TransactionHash, transfer.TraceIndex)
+}
+
+// snapshot returns the records that rebuild the current state when replayed
+// into an empty storage
+func (ms *MemoryStorage) snapshot() []logRecord {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var recs []logRecord
+	for i := range ms.checkpoints {
+		recs = append(recs, logRecord{Op: opSaveCheckpoint, Checkpoint: &ms.checkpoints[i]})
+	}
+	for address, txs := range ms.transactions {
+		for i := range txs {
+			recs = append(recs, logRecord{Op: opStoreTransaction, Address: address, Transaction: &txs[i]})
+		}
+	}
+	for address, transfers := range ms.transfers {
+		for i := range transfers {
+			recs = append(recs, logRecord{Op: opStoreTokenTransfer, Address: address, Transfer: &transfers[i]})
+		}
+	}
+	for address, transfers := range ms.internalTransfers {
+		for i := range transfers {
+			recs = append(recs, logRecord{Op: opStoreInternalTransfer, Address: address, Internal: &transfers[i]})
+		}
+	}
+	for address, txs := range ms.pending {
+		for i := range txs {
+			recs = append(recs, logRecord{Op: opSavePendingTransaction, Address: address, Pending: &txs[i]})
+		}
+	}
+	for i := range ms.disagreements {
+		recs = append(recs, logRecord{Op: opSaveDisagreement, Disagreement: &ms.disagreements[i]})
+	}
+	for _, id := range ms.jobOrder {
+		job := ms.backfillJobs[id]
+		recs = append(recs, logRecord{Op: opSaveBackfillJob, BackfillJob: &job})
+	}
+	return recs
 }
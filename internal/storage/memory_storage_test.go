@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"fmt"
 	"math/big"
 	"testing"
 
@@ -56,3 +57,75 @@ func TestMemoryStorage(t *testing.T) {
 		t.Errorf("Expected 2 transactions, got %d", len(txs))
 	}
 }
+
+func TestMemoryStorageRemoveTransactionsFrom(t *testing.T) {
+	storage := NewMemoryStorage()
+
+	storage.StoreTransaction("0xSender", types.Transaction{Hash: "0x1", BlockNumber: 100})
+	storage.StoreTransaction("0xSender", types.Transaction{Hash: "0x2", BlockNumber: 101})
+	storage.StoreTransaction("0xReceiver", types.Transaction{Hash: "0x2", BlockNumber: 101})
+	before, _ := storage.GetTransactions("0xSender")
+
+	removed, err := storage.RemoveTransactionsFrom(101)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(removed["0xSender"]) != 1 || len(removed["0xReceiver"]) != 1 {
+		t.Errorf("Unexpected removed transactions: %v", removed)
+	}
+
+	txs, _ := storage.GetTransactions("0xSender")
+	if len(txs) != 1 || txs[0].Hash != "0x1" {
+		t.Errorf("Expected only 0x1 to remain, got %v", txs)
+	}
+
+	// Slices handed out earlier are not rewritten
+	if len(before) != 2 || before[1].Hash != "0x2" {
+		t.Errorf("Expected earlier result to be unchanged, got %v", before)
+	}
+}
+
+func TestMemoryStorageSkipsDuplicates(t *testing.T) {
+	storage := NewMemoryStorage()
+
+	tx := types.Transaction{Hash: "0x123", BlockNumber: 100}
+	if stored, _ := storage.StoreTransaction("0xSender", tx); !stored {
+		t.Errorf("Expected the first store to report a new transaction")
+	}
+	if stored, _ := storage.StoreTransaction("0xSender", tx); stored {
+		t.Errorf("Expected the duplicate to be reported as not new")
+	}
+
+	txs, _ := storage.GetTransactions("0xSender")
+	if len(txs) != 1 {
+		t.Errorf("Expected 1 transaction, got %d", len(txs))
+	}
+}
+
+func TestMemoryStorageConfirmationWatermarks(t *testing.T) {
+	storage := NewMemoryStorage()
+
+	for i, block := range []int64{99, 100, 101} {
+		tx := types.Transaction{Hash: fmt.Sprintf("0x%d", i), BlockNumber: block, Confirmation: types.StatusPendingConfirmation}
+		storage.StoreTransaction("0xSender", tx)
+	}
+	before, _ := storage.GetTransactions("0xSender")
+
+	cp := types.Checkpoint{BlockNumber: 101, BlockHash: "0x101", ConfirmedThrough: 100, FinalizedThrough: 99}
+	if err := storage.SaveCheckpoint(cp); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	txs, _ := storage.GetTransactions("0xSender")
+	want := []types.ConfirmationStatus{types.StatusFinalized, types.StatusConfirmed, types.StatusPendingConfirmation}
+	for i, tx := range txs {
+		if tx.Confirmation != want[i] {
+			t.Errorf("Block %d: expected %s, got %s", tx.BlockNumber, want[i], tx.Confirmation)
+		}
+	}
+	// Slices handed out earlier are not rewritten
+	if before[0].Confirmation != types.StatusPendingConfirmation {
+		t.Errorf("Expected earlier result to be unchanged, got %s", before[0].Confirmation)
+	}
+}
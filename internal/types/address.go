@@ -0,0 +1,44 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Address is an Ethereum address in canonical form: "0x" followed by 40
+// lowercase hex digits. Use ParseAddress to obtain one from user input.
+type Address string
+
+// ParseAddress validates s and returns its canonical form. Addresses in a
+// single case are accepted as is; mixed-case addresses must carry a valid
+// EIP-55 checksum.
+func ParseAddress(s string) (Address, error) {
+	if len(s) != 2+2*common.AddressLength || (s[:2] != "0x" && s[:2] != "0X") {
+		return "", fmt.Errorf("invalid Ethereum address %q: expected 0x followed by 40 hex digits", s)
+	}
+
+	digits := s[2:]
+	if !common.IsHexAddress(digits) {
+		return "", fmt.Errorf("invalid Ethereum address %q: not a hex string", s)
+	}
+
+	lower := strings.ToLower(digits)
+	if digits != lower && digits != strings.ToUpper(digits) {
+		if checksummed := common.HexToAddress(lower).Hex(); checksummed[2:] != digits {
+			return "", fmt.Errorf("invalid Ethereum address %q: EIP-55 checksum mismatch", s)
+		}
+	}
+
+	return Address("0x" + lower), nil
+}
+
+// Hex returns the EIP-55 checksummed representation of the address
+func (a Address) Hex() string {
+	return common.HexToAddress(string(a)).Hex()
+}
+
+func (a Address) String() string {
+	return string(a)
+}
@@ -0,0 +1,40 @@
+package types
+
+import "testing"
+
+func TestParseAddress(t *testing.T) {
+	canonical := Address("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+
+	valid := []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", // EIP-55 checksummed
+		"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+		"0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED",
+	}
+	for _, s := range valid {
+		address, err := ParseAddress(s)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", s, err)
+			continue
+		}
+		if address != canonical {
+			t.Errorf("Expected %s, got %s", canonical, address)
+		}
+	}
+
+	invalid := []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", // bad checksum
+		"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaeg", // not hex
+		"0x5aaeb6053f3e94c9b9a09f33669435e7ef1bea",   // too short
+		"5aaeb6053f3e94c9b9a09f33669435e7ef1beaed00", // no prefix
+		"",
+	}
+	for _, s := range invalid {
+		if _, err := ParseAddress(s); err == nil {
+			t.Errorf("Expected an error for %q", s)
+		}
+	}
+
+	if hex := canonical.Hex(); hex != valid[0] {
+		t.Errorf("Expected checksummed address %s, got %s", valid[0], hex)
+	}
+}
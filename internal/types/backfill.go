@@ -0,0 +1,23 @@
+package types
+
+// BackfillStatus is the state of a backfill job
+type BackfillStatus string
+
+const (
+	BackfillRunning   BackfillStatus = "running"
+	BackfillCompleted BackfillStatus = "completed"
+	BackfillFailed    BackfillStatus = "failed"
+)
+
+// BackfillJob scans a historical block range for transactions of the given
+// addresses. NextBlock is the first block not yet processed, which allows an
+// interrupted job to resume where it stopped.
+type BackfillJob struct {
+	ID        string         `json:"id"`
+	Addresses []Address      `json:"addresses"`
+	FromBlock int64          `json:"fromBlock"`
+	ToBlock   int64          `json:"toBlock"`
+	NextBlock int64          `json:"nextBlock"`
+	Status    BackfillStatus `json:"status"`
+	Error     string         `json:"error,omitempty"`
+}
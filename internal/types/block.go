@@ -0,0 +1,39 @@
+package types
+
+import "math/big"
+
+// BlockHeader holds the block header fields used to track chain progress
+// and describe the block a transaction was included in
+type BlockHeader struct {
+	Number     int64
+	Hash       string
+	ParentHash string
+	// Timestamp is the block time in seconds since the Unix epoch
+	Timestamp int64
+	// BaseFeePerGas is nil for blocks before the London fork
+	BaseFeePerGas *big.Int
+	Miner         Address
+	GasUsed       uint64
+	GasLimit      uint64
+	// WithdrawalsRoot is empty for blocks before the Shanghai fork
+	WithdrawalsRoot string
+	// BlobGasUsed and ExcessBlobGas are nil for blocks before the Cancun fork
+	BlobGasUsed   *uint64
+	ExcessBlobGas *uint64
+}
+
+// Checkpoint records the last block whose transactions were fully processed
+type Checkpoint struct {
+	BlockNumber int64
+	BlockHash   string
+	// ConfirmedThrough and FinalizedThrough are the highest confirmed and
+	// finalized blocks when the checkpoint was saved, -1 if unknown
+	ConfirmedThrough int64
+	FinalizedThrough int64
+}
+
+// Block is a block header together with the block's transactions
+type Block struct {
+	BlockHeader
+	Transactions []Transaction
+}
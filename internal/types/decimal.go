@@ -0,0 +1,31 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// decimalInt encodes a big.Int as a JSON decimal string, so that amounts
+// above 2^53 survive clients that parse JSON numbers as doubles
+type decimalInt big.Int
+
+func (d *decimalInt) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*big.Int)(d).String())
+}
+
+// UnmarshalJSON also accepts bare JSON numbers, which is how amounts were
+// encoded before they were serialized as strings
+func (d *decimalInt) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+
+	if _, ok := (*big.Int)(d).SetString(s, 10); !ok {
+		return fmt.Errorf("invalid decimal integer %s", data)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package types
+
+// DecodedCall is the method and arguments of a contract call, decoded from
+// the transaction input data
+type DecodedCall struct {
+	Method    string       `json:"method"`
+	Signature string       `json:"signature"`
+	Args      []DecodedArg `json:"args,omitempty"`
+	// Error is set when the input does not match the method's arguments
+	Error string `json:"error,omitempty"`
+}
+
+// DecodedArg is a single decoded call argument. Integers are decimal
+// strings and byte strings are hex encoded.
+type DecodedArg struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
@@ -0,0 +1,22 @@
+package types
+
+// DisagreementBlock is the Subject of a disagreement about a block hash
+const DisagreementBlock = "block"
+
+// Disagreement records RPC providers that answered differently for the same
+// block or transaction in quorum mode. The block is not indexed until they
+// agree.
+type Disagreement struct {
+	BlockNumber int64 `json:"blockNumber"`
+	// Subject is DisagreementBlock for the block hash, or the hash of a
+	// transaction of the block
+	Subject string `json:"subject"`
+	// Expected is the value of the block that was about to be indexed
+	Expected string `json:"expected"`
+	// Answers maps each provider to the value it returned
+	Answers map[string]string `json:"answers"`
+	// FirstSeen and LastSeen are the Unix times the disagreement was first
+	// and last observed
+	FirstSeen int64 `json:"firstSeen"`
+	LastSeen  int64 `json:"lastSeen"`
+}
@@ -0,0 +1,51 @@
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+)
+
+// InternalTransfer is a value-bearing call made by a contract during the
+// execution of a transaction, extracted from the transaction's call trace.
+// Values are serialized as decimal strings.
+type InternalTransfer struct {
+	// TransactionHash is the hash of the top-level transaction the call
+	// belongs to
+	TransactionHash string `json:"transactionHash"`
+	// TraceIndex is the position of the call among the transaction's
+	// internal transfers, in depth-first order of the call tree
+	TraceIndex int `json:"traceIndex"`
+	// Depth is the call depth; calls made directly by the top-level call
+	// have depth 1
+	Depth int `json:"depth"`
+	// CallType is the lowercased call kind, e.g. call, create or selfdestruct
+	CallType     string             `json:"callType"`
+	BlockNumber  int64              `json:"blockNumber"`
+	BlockHash    string             `json:"blockHash,omitempty"`
+	From         Address            `json:"from"`
+	To           Address            `json:"to"`
+	Value        *big.Int           `json:"value"`
+	Confirmation ConfirmationStatus `json:"confirmation,omitempty"`
+}
+
+func (t InternalTransfer) MarshalJSON() ([]byte, error) {
+	type plain InternalTransfer
+	return json.Marshal(struct {
+		plain
+		Value *decimalInt `json:"value"`
+	}{plain(t), (*decimalInt)(t.Value)})
+}
+
+func (t *InternalTransfer) UnmarshalJSON(data []byte) error {
+	type plain InternalTransfer
+	aux := struct {
+		*plain
+		Value *decimalInt `json:"value"`
+	}{plain: (*plain)(t)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	t.Value = (*big.Int)(aux.Value)
+	return nil
+}
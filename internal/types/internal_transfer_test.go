@@ -0,0 +1,32 @@
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestInternalTransferJSON(t *testing.T) {
+	value, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	transfer := InternalTransfer{TransactionHash: "0xabc", TraceIndex: 2, Depth: 1, CallType: "call", Value: value}
+
+	data, err := json.Marshal(transfer)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, field := range []string{`"transactionHash":"0xabc"`, `"traceIndex":2`, `"callType":"call"`, `"value":"123456789012345678901234567890"`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("Expected %s in %s", field, data)
+		}
+	}
+
+	// Transfers stored before the JSON tags were added
+	var legacy InternalTransfer
+	if err := json.Unmarshal([]byte(`{"TransactionHash":"0xdef","TraceIndex":1,"Value":1000}`), &legacy); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if legacy.TransactionHash != "0xdef" || legacy.TraceIndex != 1 || legacy.Value.Int64() != 1000 {
+		t.Errorf("Unexpected legacy transfer: %+v", legacy)
+	}
+}
@@ -0,0 +1,24 @@
+package types
+
+// Log is an event log emitted by a contract
+type Log struct {
+	Address         Address
+	Topics          []string
+	Data            string
+	BlockNumber     int64
+	BlockHash       string
+	TransactionHash string
+	LogIndex        uint64
+	Removed         bool
+}
+
+// LogFilter selects logs for eth_getLogs. Either BlockHash or the
+// FromBlock/ToBlock range is used. Each Topics position lists the accepted
+// values; a nil position matches any topic.
+type LogFilter struct {
+	BlockHash string
+	FromBlock int64
+	ToBlock   int64
+	Addresses []Address
+	Topics    [][]string
+}
@@ -1,8 +1,19 @@
 package types
 
-// Parser defines the interface for blockchain transaction parsing
+import "context"
+
+// Parser defines the interface for blockchain transaction parsing. The
+// context of each call bounds the RPC calls made on its behalf.
 type Parser interface {
-	GetCurrentBlock() (int64, error)
-	Subscribe(address string) bool
-	GetTransactions(address string) ([]Transaction, error)
+	GetCurrentBlock(ctx context.Context) (int64, error)
+	Subscribe(ctx context.Context, address Address) bool
+	GetTransactions(ctx context.Context, address Address) ([]Transaction, error)
+	GetPendingTransactions(ctx context.Context, address Address) ([]PendingTransaction, error)
+	GetTokenTransfers(ctx context.Context, address Address) ([]TokenTransfer, error)
+	GetInternalTransfers(ctx context.Context, address Address) ([]InternalTransfer, error)
+	// StartBackfill creates a backfill job; the job itself keeps running
+	// after ctx is done
+	StartBackfill(ctx context.Context, addresses []Address, fromBlock, toBlock int64) (*BackfillJob, error)
+	GetBackfillJob(ctx context.Context, id string) (*BackfillJob, error)
+	GetDisagreements(ctx context.Context) ([]Disagreement, error)
 }
@@ -0,0 +1,30 @@
+package types
+
+// PendingStatus is the lifecycle state of a transaction first seen in the
+// mempool
+type PendingStatus string
+
+const (
+	// PendingStatusPending is a transaction waiting in the mempool
+	PendingStatusPending PendingStatus = "pending"
+	// PendingStatusMined is a transaction that was included in a block
+	PendingStatusMined PendingStatus = "mined"
+	// PendingStatusReplaced is a transaction superseded by another
+	// transaction from the same sender with the same nonce
+	PendingStatusReplaced PendingStatus = "replaced"
+	// PendingStatusDropped is a transaction the node no longer knows about
+	PendingStatusDropped PendingStatus = "dropped"
+)
+
+// PendingTransaction is a transaction seen in the mempool together with its
+// reconciliation against the mined chain
+type PendingTransaction struct {
+	Transaction Transaction   `json:"transaction"`
+	Status      PendingStatus `json:"status"`
+	// FirstSeen is the Unix time the transaction was first seen
+	FirstSeen int64 `json:"firstSeen"`
+	// BlockNumber is the block that included the transaction once mined
+	BlockNumber int64 `json:"blockNumber,omitempty"`
+	// ReplacedBy is the hash of the mined transaction that replaced it
+	ReplacedBy string `json:"replacedBy,omitempty"`
+}
@@ -0,0 +1,49 @@
+package types
+
+import "math/big"
+
+// ReceiptStatus is the execution outcome of a mined transaction
+type ReceiptStatus string
+
+const (
+	ReceiptSuccess ReceiptStatus = "success"
+	ReceiptFailed  ReceiptStatus = "failed"
+)
+
+// Receipt holds the execution results of a mined transaction
+type Receipt struct {
+	TransactionHash   string
+	Status            ReceiptStatus
+	GasUsed           uint64
+	CumulativeGasUsed uint64
+	EffectiveGasPrice *big.Int
+	// BlobFee is the fee paid for EIP-4844 blob gas, nil for other transactions
+	BlobFee         *big.Int
+	ContractAddress Address
+}
+
+// ApplyReceipt copies the execution results of r onto tx and computes the
+// fee actually paid: gasUsed * effectiveGasPrice plus any blob fee.
+// Pre-London receipts have no effectiveGasPrice; the gas price of the
+// transaction is what was paid then.
+func (tx *Transaction) ApplyReceipt(r Receipt) {
+	tx.Status = r.Status
+	tx.GasUsed = r.GasUsed
+	tx.CumulativeGasUsed = r.CumulativeGasUsed
+	tx.ContractAddress = r.ContractAddress
+
+	price := r.EffectiveGasPrice
+	if price == nil {
+		price = tx.GasPrice
+	}
+	tx.EffectiveGasPrice = price
+	if price == nil {
+		return
+	}
+
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(r.GasUsed), price)
+	if r.BlobFee != nil {
+		fee.Add(fee, r.BlobFee)
+	}
+	tx.TransactionFee = fee
+}
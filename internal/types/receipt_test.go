@@ -0,0 +1,30 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestApplyReceiptFee(t *testing.T) {
+	tests := []struct {
+		name     string
+		gasPrice *big.Int
+		receipt  Receipt
+		want     *big.Int
+	}{
+		{"effective gas price", big.NewInt(30), Receipt{GasUsed: 21000, EffectiveGasPrice: big.NewInt(20)}, big.NewInt(420000)},
+		{"pre-London receipt", big.NewInt(30), Receipt{GasUsed: 21000}, big.NewInt(630000)},
+		{"blob fee", nil, Receipt{GasUsed: 21000, EffectiveGasPrice: big.NewInt(20), BlobFee: big.NewInt(5)}, big.NewInt(420005)},
+		{"no gas price", nil, Receipt{GasUsed: 21000}, nil},
+	}
+
+	for _, tt := range tests {
+		tx := Transaction{GasPrice: tt.gasPrice}
+		tx.ApplyReceipt(tt.receipt)
+
+		if (tx.TransactionFee == nil) != (tt.want == nil) ||
+			(tt.want != nil && tx.TransactionFee.Cmp(tt.want) != 0) {
+			t.Errorf("%s: expected fee %v, got %v", tt.name, tt.want, tx.TransactionFee)
+		}
+	}
+}
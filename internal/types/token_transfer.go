@@ -0,0 +1,65 @@
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+)
+
+// TokenStandard identifies the token interface that emitted a transfer
+type TokenStandard string
+
+const (
+	StandardERC20   TokenStandard = "erc20"
+	StandardERC721  TokenStandard = "erc721"
+	StandardERC1155 TokenStandard = "erc1155"
+)
+
+// TokenTransfer is a token movement decoded from an ERC-20 or ERC-721
+// Transfer log or an ERC-1155 TransferSingle/TransferBatch log. Token IDs
+// and amounts are serialized as decimal strings.
+type TokenTransfer struct {
+	TransactionHash string `json:"transactionHash"`
+	LogIndex        uint64 `json:"logIndex"`
+	// BatchIndex is the position of the transfer within an ERC-1155
+	// TransferBatch log, zero for every other log
+	BatchIndex  int           `json:"batchIndex"`
+	BlockNumber int64         `json:"blockNumber"`
+	BlockHash   string        `json:"blockHash,omitempty"`
+	Standard    TokenStandard `json:"standard"`
+	// Token is the address of the token contract that emitted the log
+	Token Address `json:"token"`
+	// Operator is the address that initiated an ERC-1155 transfer
+	Operator Address `json:"operator,omitempty"`
+	From     Address `json:"from"`
+	To       Address `json:"to"`
+	// TokenID is the NFT identifier, nil for ERC-20 transfers
+	TokenID *big.Int `json:"tokenId,omitempty"`
+	// Amount is the number of tokens moved; always 1 for ERC-721
+	Amount       *big.Int           `json:"amount"`
+	Confirmation ConfirmationStatus `json:"confirmation,omitempty"`
+}
+
+func (t TokenTransfer) MarshalJSON() ([]byte, error) {
+	type plain TokenTransfer
+	return json.Marshal(struct {
+		plain
+		TokenID *decimalInt `json:"tokenId,omitempty"`
+		Amount  *decimalInt `json:"amount"`
+	}{plain(t), (*decimalInt)(t.TokenID), (*decimalInt)(t.Amount)})
+}
+
+func (t *TokenTransfer) UnmarshalJSON(data []byte) error {
+	type plain TokenTransfer
+	aux := struct {
+		*plain
+		TokenID *decimalInt `json:"tokenId,omitempty"`
+		Amount  *decimalInt `json:"amount"`
+	}{plain: (*plain)(t)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	t.TokenID = (*big.Int)(aux.TokenID)
+	t.Amount = (*big.Int)(aux.Amount)
+	return nil
+}
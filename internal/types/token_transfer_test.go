@@ -0,0 +1,46 @@
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestTokenTransferJSON(t *testing.T) {
+	tokenID, _ := new(big.Int).SetString("115792089237316195423570985008687907853269984665640564039457584007913129639935", 10)
+	transfer := TokenTransfer{
+		TransactionHash: "0xabc",
+		LogIndex:        3,
+		Standard:        StandardERC721,
+		TokenID:         tokenID,
+		Amount:          big.NewInt(1),
+	}
+
+	data, err := json.Marshal(transfer)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, field := range []string{`"transactionHash":"0xabc"`, `"logIndex":3`, `"tokenId":"` + tokenID.String() + `"`, `"amount":"1"`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("Expected %s in %s", field, data)
+		}
+	}
+
+	var decoded TokenTransfer
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if decoded.TokenID.Cmp(tokenID) != 0 || decoded.Amount.Int64() != 1 || decoded.LogIndex != 3 {
+		t.Errorf("Unexpected round trip: %+v", decoded)
+	}
+
+	// Transfers stored before the JSON tags were added
+	var legacy TokenTransfer
+	if err := json.Unmarshal([]byte(`{"TransactionHash":"0xdef","Amount":1000,"TokenID":null}`), &legacy); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if legacy.TransactionHash != "0xdef" || legacy.Amount.Int64() != 1000 || legacy.TokenID != nil {
+		t.Errorf("Unexpected legacy transfer: %+v", legacy)
+	}
+}
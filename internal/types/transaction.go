@@ -1,14 +1,180 @@
 package types
 
-import "math/big"
+import (
+	"encoding/json"
+	"math/big"
+)
 
-// Transaction represents an Ethereum blockchain transaction
+// ConfirmationStatus describes how final the block including a transaction is
+type ConfirmationStatus string
+
+const (
+	// StatusPendingConfirmation is a mined transaction without enough confirmations yet
+	StatusPendingConfirmation ConfirmationStatus = "pending-confirmation"
+	// StatusConfirmed is a transaction with the configured number of confirmations
+	StatusConfirmed ConfirmationStatus = "confirmed"
+	// StatusFinalized is a transaction at or below the finalized block
+	StatusFinalized ConfirmationStatus = "finalized"
+)
+
+var confirmationRanks = map[ConfirmationStatus]int{
+	StatusPendingConfirmation: 1,
+	StatusConfirmed:           2,
+	StatusFinalized:           3,
+}
+
+// IsValid reports whether s is a known confirmation status
+func (s ConfirmationStatus) IsValid() bool {
+	_, ok := confirmationRanks[s]
+	return ok
+}
+
+// AtLeast reports whether s is as final as other
+func (s ConfirmationStatus) AtLeast(other ConfirmationStatus) bool {
+	return confirmationRanks[s] >= confirmationRanks[other]
+}
+
+// TxType is the EIP-2718 transaction type
+type TxType uint8
+
+const (
+	TxTypeLegacy     TxType = 0
+	TxTypeAccessList TxType = 1 // EIP-2930
+	TxTypeDynamicFee TxType = 2 // EIP-1559
+	TxTypeBlob       TxType = 3 // EIP-4844
+	TxTypeSetCode    TxType = 4 // EIP-7702
+)
+
+// AccessTuple is an EIP-2930 access list entry
+type AccessTuple struct {
+	Address     Address  `json:"address"`
+	StorageKeys []string `json:"storageKeys"`
+}
+
+// Authorization is an EIP-7702 authorization to set the code of the
+// signing account
+type Authorization struct {
+	ChainID *big.Int `json:"chainId"`
+	Address Address  `json:"address"`
+	Nonce   uint64   `json:"nonce"`
+	YParity uint64   `json:"yParity"`
+	R       string   `json:"r"`
+	S       string   `json:"s"`
+}
+
+// Transaction represents an Ethereum blockchain transaction. Fields that do
+// not apply to the transaction type are left empty. Wei amounts are
+// serialized as decimal strings.
 type Transaction struct {
-	Hash           string
-	From           string
-	To             string
-	Value          *big.Int
-	BlockNumber    int64
-	Timestamp      int64
-	TransactionFee *big.Int
+	Hash string  `json:"hash"`
+	Type TxType  `json:"type"`
+	From Address `json:"from"`
+	// To is empty for contract creations
+	To               Address  `json:"to,omitempty"`
+	Value            *big.Int `json:"value"`
+	Nonce            uint64   `json:"nonce"`
+	Gas              uint64   `json:"gas"`
+	Input            string   `json:"input"`
+	BlockNumber      int64    `json:"blockNumber"`
+	BlockHash        string   `json:"blockHash,omitempty"`
+	TransactionIndex uint64   `json:"transactionIndex"`
+	Timestamp        int64    `json:"timestamp"`
+	// ChainID is nil for legacy transactions signed without EIP-155
+	// replay protection
+	ChainID *big.Int `json:"chainId,omitempty"`
+
+	// GasPrice is set for legacy and access list transactions
+	GasPrice *big.Int `json:"gasPrice,omitempty"`
+	// MaxFeePerGas and MaxPriorityFeePerGas are set from EIP-1559 on
+	MaxFeePerGas         *big.Int `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *big.Int `json:"maxPriorityFeePerGas,omitempty"`
+	// MaxFeePerBlobGas and BlobVersionedHashes are set for blob transactions
+	MaxFeePerBlobGas    *big.Int        `json:"maxFeePerBlobGas,omitempty"`
+	BlobVersionedHashes []string        `json:"blobVersionedHashes,omitempty"`
+	AccessList          []AccessTuple   `json:"accessList,omitempty"`
+	AuthorizationList   []Authorization `json:"authorizationList,omitempty"`
+
+	TransactionFee *big.Int           `json:"transactionFee,omitempty"`
+	Confirmation   ConfirmationStatus `json:"confirmation,omitempty"`
+
+	// Receipt fields, empty until the receipt has been fetched
+	Status            ReceiptStatus `json:"status,omitempty"`
+	GasUsed           uint64        `json:"gasUsed,omitempty"`
+	CumulativeGasUsed uint64        `json:"cumulativeGasUsed,omitempty"`
+	EffectiveGasPrice *big.Int      `json:"effectiveGasPrice,omitempty"`
+	ContractAddress   Address       `json:"contractAddress,omitempty"`
+
+	// Decoded is the decoded contract call, set when the transactions are
+	// queried and the input matches a known method
+	Decoded *DecodedCall `json:"decoded,omitempty"`
+}
+
+// plainTransaction has the fields of Transaction without its JSON methods
+type plainTransaction Transaction
+
+// transactionJSON overrides the wei amounts of a transaction with their
+// decimal string encoding
+type transactionJSON struct {
+	*plainTransaction
+	Value                *decimalInt `json:"value"`
+	ChainID              *decimalInt `json:"chainId,omitempty"`
+	GasPrice             *decimalInt `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *decimalInt `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *decimalInt `json:"maxPriorityFeePerGas,omitempty"`
+	MaxFeePerBlobGas     *decimalInt `json:"maxFeePerBlobGas,omitempty"`
+	TransactionFee       *decimalInt `json:"transactionFee,omitempty"`
+	EffectiveGasPrice    *decimalInt `json:"effectiveGasPrice,omitempty"`
+}
+
+func (tx Transaction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(transactionJSON{
+		plainTransaction:     (*plainTransaction)(&tx),
+		Value:                (*decimalInt)(tx.Value),
+		ChainID:              (*decimalInt)(tx.ChainID),
+		GasPrice:             (*decimalInt)(tx.GasPrice),
+		MaxFeePerGas:         (*decimalInt)(tx.MaxFeePerGas),
+		MaxPriorityFeePerGas: (*decimalInt)(tx.MaxPriorityFeePerGas),
+		MaxFeePerBlobGas:     (*decimalInt)(tx.MaxFeePerBlobGas),
+		TransactionFee:       (*decimalInt)(tx.TransactionFee),
+		EffectiveGasPrice:    (*decimalInt)(tx.EffectiveGasPrice),
+	})
+}
+
+func (tx *Transaction) UnmarshalJSON(data []byte) error {
+	aux := transactionJSON{plainTransaction: (*plainTransaction)(tx)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	tx.Value = (*big.Int)(aux.Value)
+	tx.ChainID = (*big.Int)(aux.ChainID)
+	tx.GasPrice = (*big.Int)(aux.GasPrice)
+	tx.MaxFeePerGas = (*big.Int)(aux.MaxFeePerGas)
+	tx.MaxPriorityFeePerGas = (*big.Int)(aux.MaxPriorityFeePerGas)
+	tx.MaxFeePerBlobGas = (*big.Int)(aux.MaxFeePerBlobGas)
+	tx.TransactionFee = (*big.Int)(aux.TransactionFee)
+	tx.EffectiveGasPrice = (*big.Int)(aux.EffectiveGasPrice)
+	return nil
+}
+
+func (a Authorization) MarshalJSON() ([]byte, error) {
+	type plain Authorization
+	return json.Marshal(struct {
+		plain
+		ChainID *decimalInt `json:"chainId"`
+	}{plain(a), (*decimalInt)(a.ChainID)})
+}
+
+func (a *Authorization) UnmarshalJSON(data []byte) error {
+	type plain Authorization
+	aux := struct {
+		*plain
+		ChainID *decimalInt `json:"chainId"`
+	}{plain: (*plain)(a)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	a.ChainID = (*big.Int)(aux.ChainID)
+	return nil
 }
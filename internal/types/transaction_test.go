@@ -0,0 +1,63 @@
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestTransactionJSON(t *testing.T) {
+	value, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	tx := Transaction{
+		Hash:                 "0xabc",
+		Type:                 TxTypeSetCode,
+		From:                 Address("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"),
+		Value:                value,
+		Gas:                  21000,
+		ChainID:              big.NewInt(1),
+		MaxFeePerGas:         big.NewInt(2000000000),
+		MaxPriorityFeePerGas: big.NewInt(1000000000),
+		AuthorizationList: []Authorization{
+			{ChainID: big.NewInt(1), Address: Address("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"), Nonce: 7, R: "0x1", S: "0x2"},
+		},
+	}
+
+	data, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, field := range []string{
+		`"value":"123456789012345678901234567890"`,
+		`"maxFeePerGas":"2000000000"`,
+		`"chainId":"1"`,
+		`"type":4`,
+	} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("Expected %s in %s", field, data)
+		}
+	}
+	if strings.Contains(string(data), "gasPrice") {
+		t.Errorf("Expected no gasPrice for a set code transaction: %s", data)
+	}
+
+	var decoded Transaction
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if decoded.Value.Cmp(value) != 0 || decoded.MaxFeePerGas.Int64() != 2000000000 || decoded.AuthorizationList[0].ChainID.Int64() != 1 {
+		t.Errorf("Unexpected round trip: %+v", decoded)
+	}
+}
+
+func TestTransactionUnmarshalNumericAmounts(t *testing.T) {
+	// Transactions stored before amounts were serialized as strings
+	var tx Transaction
+	if err := json.Unmarshal([]byte(`{"Hash":"0x123","Value":1000,"TransactionFee":null}`), &tx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tx.Hash != "0x123" || tx.Value.Int64() != 1000 || tx.TransactionFee != nil {
+		t.Errorf("Unexpected transaction: %+v", tx)
+	}
+}